@@ -11,15 +11,19 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/channel"
 	"github.com/zhaoxinyi02/ClawPanel/internal/config"
 	"github.com/zhaoxinyi02/ClawPanel/internal/handler"
 	"github.com/zhaoxinyi02/ClawPanel/internal/middleware"
 	"github.com/zhaoxinyi02/ClawPanel/internal/model"
 	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/ingest"
 	"github.com/zhaoxinyi02/ClawPanel/internal/process"
 	"github.com/zhaoxinyi02/ClawPanel/internal/taskman"
+	"github.com/zhaoxinyi02/ClawPanel/internal/webhook"
 	"github.com/zhaoxinyi02/ClawPanel/internal/websocket"
 )
 
@@ -43,21 +47,98 @@ func main() {
 	// 初始化进程管理器
 	procMgr := process.NewManager(cfg)
 
-	// 初始化 WebSocket Hub
+	// 初始化 WebSocket Hub，所有连接需携带有效 JWT，令牌过期时断开
 	wsHub := websocket.NewHub()
+	wsHub.SetAuth(func(token string) (time.Time, error) {
+		claims, err := middleware.ParseToken(cfg.JWTSecret, token)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if claims.ExpiresAt == nil {
+			return time.Time{}, nil
+		}
+		return claims.ExpiresAt.Time, nil
+	})
+	wsHub.SetAllowedOrigins(cfg.GetAllowedOrigins)
 	go wsHub.Run()
 
 	// 初始化任务管理器
 	taskMgr := taskman.NewManager(wsHub)
 
-	// 初始化系统事件日志
-	sysLog := eventlog.NewSystemLogger(db, wsHub)
+	// 外发 Webhook：持久化投递队列，重启后继续发送未完成的投递
+	webhooks := webhook.NewDispatcher(db)
+	webhooks.Start()
+	defer webhooks.Stop()
+
+	// 初始化系统事件日志，活动日志统一经由 publisher 保存、推送和投递
+	publisher := eventlog.NewPublisher(db, wsHub, webhooks)
+	sysLog := eventlog.NewSystemLogger(publisher)
+	webhooks.OnFailure(func(hook model.Webhook, d model.WebhookDelivery) {
+		sysLog.LogDetail("system", "webhook.delivery.failed",
+			fmt.Sprintf("Webhook %s 投递失败（已重试 %d 次）: %s", hook.Name, d.Attempts, d.LastError),
+			fmt.Sprintf("delivery=%d event=%s url=%s", d.ID, d.EventType, hook.URL))
+	})
 	sysLog.Log("system", "panel.start", "ClawPanel 管理面板已启动")
 
-	// 启动 OneBot11 事件监听器 (监听 NapCat WebSocket 消息并记录到活动日志)
-	evListener := eventlog.NewListener(db, wsHub, "ws://127.0.0.1:3001")
-	evListener.Start()
-	defer evListener.Stop()
+	// 活动日志按保留策略定期清理
+	pruner := eventlog.NewPruner(db, sysLog)
+	pruner.Start()
+	defer pruner.Stop()
+
+	// 外部推送活动日志的接入密钥
+	ingestGuard := ingest.NewGuard(db)
+
+	// 消息统计按小时汇总
+	statsRoller := eventlog.NewStatsRoller(db)
+	statsRoller.Start()
+	defer statsRoller.Stop()
+
+	// 注册通道适配器
+	qqInstances := channel.LoadQQInstances(cfg)
+	channel.Register(channel.NewWechatAdapter(cfg))
+
+	// 每个 NapCat 实例启动一个 OneBot11 事件监听器 (监听 NapCat WebSocket 消息并记录到活动日志)
+	reverseCfg := cfg.GetOneBotReverse()
+	evListeners := eventlog.Listeners{}
+	for _, qq := range qqInstances {
+		inst := qq.Instance()
+		l := eventlog.NewListener(db, publisher, inst.OneBotWS)
+		if len(qqInstances) > 1 {
+			l.SetInstance(inst.ID, inst.Name)
+		} else {
+			l.SetInstance(inst.ID, "")
+		}
+		l.OnSelfID(qq.SetSelfID)
+		go qq.ResolveSelfID()
+		instanceID := inst.ID
+		l.SetAccessToken(func() string { return cfg.OneBotAccessToken(instanceID) })
+		qq.OneBot().OnAuthFailure(func(err error) { l.ReportAuthFailure("http", err) })
+		l.SetRequestPolicy(cfg.DataDir, qq.OneBot())
+		if !reverseCfg.Enabled || !reverseCfg.DisableForward {
+			l.Start()
+			defer l.Stop()
+		}
+		evListeners[inst.ID] = l
+	}
+
+	// OneBot11 反向 WebSocket：按 X-Self-ID 找到对应账号的监听器，未匹配时交给第一个实例
+	var reverseWS *eventlog.ReverseServer
+	if reverseCfg.Enabled {
+		reverseWS = eventlog.NewReverseServer(
+			func() string { return cfg.GetOneBotReverse().AccessToken },
+			func(selfID string) *eventlog.Listener {
+				if qq, ok := channel.QQInstanceBySelfID(selfID); ok {
+					return evListeners[qq.InstanceID()]
+				}
+				if len(qqInstances) > 1 {
+					log.Printf("[EventLog] 反向 WS self_id=%s 未匹配任何 NapCat 实例，交给默认实例 %s", selfID, qqInstances[0].InstanceID())
+				}
+				return evListeners[qqInstances[0].InstanceID()]
+			},
+			sysLog,
+		)
+		defer reverseWS.Close()
+	}
 
 	// 设置 Gin 模式
 	if cfg.Debug {
@@ -85,7 +166,7 @@ func main() {
 			auth.POST("/auth/change-password", handler.ChangePassword(db, cfg))
 
 			// 状态总览
-			auth.GET("/status", handler.GetStatus(db, cfg, procMgr))
+			auth.GET("/status", handler.GetStatus(db, cfg, procMgr, evListeners))
 
 			// OpenClaw 配置
 			auth.GET("/openclaw/config", handler.GetOpenClawConfig(cfg))
@@ -141,6 +222,44 @@ func main() {
 			// 事件日志
 			auth.GET("/events", handler.GetEvents(db))
 			auth.POST("/events/clear", handler.ClearEvents(db))
+			auth.GET("/events/retention", handler.GetEventRetention(db))
+			auth.PUT("/events/retention", handler.SaveEventRetention(db, sysLog))
+			auth.GET("/events/storage", handler.GetEventStorage(db))
+			auth.POST("/events/prune", handler.PruneEvents(pruner))
+			auth.GET("/events/export", handler.ExportEvents(db))
+			auth.POST("/events/import", handler.ImportEvents(db, sysLog))
+
+			// 活动日志接入密钥
+			auth.GET("/ingest/keys", handler.GetIngestKeys(db))
+			auth.POST("/ingest/keys", handler.CreateIngestKey(db, ingestGuard, sysLog))
+			auth.PUT("/ingest/keys/:id", handler.UpdateIngestKey(db, ingestGuard, sysLog))
+			auth.DELETE("/ingest/keys/:id", handler.DeleteIngestKey(db, ingestGuard, sysLog))
+			auth.POST("/ingest/keys/:id/rotate", handler.RotateIngestKey(db, ingestGuard, sysLog))
+
+			// 消息统计
+			auth.GET("/stats/overview", handler.GetStatsOverview(db, statsRoller))
+			auth.GET("/stats/messages", handler.GetStatsMessages(db, statsRoller))
+			auth.GET("/stats/groups", handler.GetStatsGroups(db, statsRoller))
+			auth.GET("/stats/users", handler.GetStatsUsers(db, statsRoller))
+
+			// 消息记录
+			auth.GET("/messages/conversations", handler.GetConversations(db))
+			auth.GET("/messages/:type/:peer", handler.GetConversationMessages(db))
+
+			// 消息告警规则
+			auth.GET("/alerts/rules", handler.GetAlertRules(db))
+			auth.POST("/alerts/rules", handler.CreateAlertRule(db, sysLog))
+			auth.PUT("/alerts/rules/:id", handler.UpdateAlertRule(db, sysLog))
+			auth.DELETE("/alerts/rules/:id", handler.DeleteAlertRule(db, sysLog))
+
+			// 外发 Webhook
+			auth.GET("/webhooks", handler.GetWebhooks(db))
+			auth.POST("/webhooks", handler.CreateWebhook(db, webhooks, sysLog))
+			auth.PUT("/webhooks/:id", handler.UpdateWebhook(db, webhooks, sysLog))
+			auth.DELETE("/webhooks/:id", handler.DeleteWebhook(db, webhooks, sysLog))
+			auth.POST("/webhooks/:id/test", handler.TestWebhook(db, webhooks))
+			auth.GET("/webhooks/deliveries", handler.GetWebhookDeliveries(db))
+			auth.POST("/webhooks/deliveries/:id/retry", handler.RetryWebhookDelivery(db, webhooks))
 
 			// Admin 配置
 			auth.GET("/admin/config", handler.GetAdminConfig(cfg))
@@ -151,6 +270,8 @@ func main() {
 			auth.GET("/system/admin-token", handler.GetAdminToken(cfg))
 			auth.GET("/system/sudo-password", handler.GetSudoPassword(cfg))
 			auth.PUT("/system/sudo-password", handler.SetSudoPassword(cfg))
+			auth.GET("/system/endpoints", handler.GetEndpoints(cfg))
+			auth.PUT("/system/endpoints", handler.SaveEndpoints(cfg, evListeners, sysLog))
 
 			// ClawHub 同步
 			auth.POST("/system/clawhub-sync", handler.ClawHubSync(cfg))
@@ -158,15 +279,25 @@ func main() {
 			// Bot 操作
 			auth.GET("/bot/groups", handler.GetBotGroups(cfg))
 			auth.GET("/bot/friends", handler.GetBotFriends(cfg))
-			auth.POST("/bot/send", handler.BotSend(cfg))
-			auth.POST("/bot/reconnect", handler.BotReconnect(cfg))
+			auth.POST("/bot/send", handler.BotSend(cfg, sysLog))
+			auth.POST("/bot/reconnect", handler.BotReconnect(cfg, evListeners))
+			auth.GET("/bot/connection", handler.GetBotConnection(evListeners))
+			auth.GET("/bot/reverse-connections", handler.GetReverseConnections(cfg, reverseWS))
+
+			// 通道适配器
+			auth.GET("/channel/:id/status", handler.GetChannelStatus())
+			auth.GET("/channel/:id/contacts", handler.GetChannelContacts())
+			auth.POST("/channel/:id/send", handler.ChannelSend(cfg, sysLog))
+			auth.POST("/channel/:id/login", handler.ChannelLogin())
+			auth.POST("/channel/:id/logout", handler.ChannelLogout())
 
 			// 请求审批
-			auth.GET("/requests", handler.GetRequests(cfg))
-			auth.POST("/requests/:flag/approve", handler.ApproveRequest(cfg))
-			auth.POST("/requests/:flag/reject", handler.RejectRequest(cfg))
+			auth.GET("/requests", handler.GetRequests(db))
+			auth.POST("/requests/:flag/approve", handler.ApproveRequest(db, cfg, sysLog))
+			auth.POST("/requests/:flag/reject", handler.RejectRequest(db, cfg, sysLog))
 
 			// NapCat QQ 登录
+			auth.GET("/napcat/instances", handler.GetNapcatInstances())
 			auth.POST("/napcat/login-status", handler.NapcatLoginStatus(cfg))
 			auth.POST("/napcat/qrcode", handler.NapcatGetQRCode(cfg))
 			auth.POST("/napcat/qrcode/refresh", handler.NapcatRefreshQRCode(cfg))
@@ -208,22 +339,28 @@ func main() {
 			auth.POST("/software/install", handler.InstallSoftware(cfg, taskMgr))
 			auth.GET("/tasks", handler.GetTasks(taskMgr))
 			auth.GET("/tasks/:id", handler.GetTaskDetail(taskMgr))
-
-			// WebSocket 实时日志
-			auth.GET("/ws/logs", wsHub.HandleWebSocket())
 		}
 
+		// WebSocket 实时日志（Hub 自行校验令牌，支持首条消息认证）
+		api.GET("/ws/logs", wsHub.HandleWebSocket())
+
 		// 工作区下载和预览（支持 token query param）
 		api.GET("/workspace/download", handler.WorkspaceDownload(cfg))
 		api.GET("/workspace/preview", handler.WorkspacePreview(cfg))
 
-		// 外部日志接口（无需认证）
-		api.POST("/events/log", handler.PostEvent(db))
+		// 外部日志接口（使用接入密钥认证，见 /api/ingest/keys）
+		api.POST("/events/log", handler.PostEvent(publisher, ingestGuard))
+		api.POST("/events/log/batch", handler.PostEventBatch(publisher, ingestGuard))
 	}
 
-	// WebSocket 路由（前端连接 /ws?token=...）
+	// WebSocket 路由（前端连接 /ws?token=...，或连接后发送 auth 消息）
 	r.GET("/ws", wsHub.HandleWebSocket())
 
+	// OneBot11 反向 WebSocket（NapCat 主动连接，access_token 鉴权）
+	if reverseWS != nil {
+		r.GET("/onebot/v11/ws", gin.WrapH(reverseWS))
+	}
+
 	// 内嵌前端静态资源
 	frontendDist, err := fs.Sub(frontendFS, "frontend/dist")
 	if err != nil {
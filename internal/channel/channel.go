@@ -0,0 +1,129 @@
+package channel
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported 通道不支持该操作
+var ErrUnsupported = errors.New("该通道不支持此操作")
+
+// Labels 通道 ID → 显示名称
+var Labels = map[string]string{
+	"qq": "QQ (NapCat)", "wechat": "微信", "whatsapp": "WhatsApp",
+	"telegram": "Telegram", "discord": "Discord", "irc": "IRC",
+	"slack": "Slack", "signal": "Signal", "googlechat": "Google Chat",
+	"webchat": "WebChat", "feishu": "飞书 / Lark", "qqbot": "QQ 官方机器人",
+	"dingtalk": "钉钉", "wecom": "企业微信", "msteams": "Microsoft Teams",
+	"mattermost": "Mattermost", "line": "LINE", "matrix": "Matrix", "twitch": "Twitch",
+}
+
+// Label 获取通道显示名称，未知通道返回 ID 本身
+func Label(id string) string {
+	if label := Labels[id]; label != "" {
+		return label
+	}
+	return id
+}
+
+// Status 通道连接与登录状态
+type Status struct {
+	Connected bool                   `json:"connected"` // 通道服务可达
+	LoggedIn  bool                   `json:"loggedIn"`
+	SelfID    string                 `json:"selfId,omitempty"`
+	Nickname  string                 `json:"nickname,omitempty"`
+	Extra     map[string]interface{} `json:"extra,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// Contact 联系人 / 群聊
+type Contact struct {
+	ID             string `json:"id"`
+	Kind           string `json:"kind"` // friend / group
+	Name           string `json:"name"`
+	Remark         string `json:"remark,omitempty"`
+	MemberCount    int    `json:"memberCount,omitempty"`
+	MaxMemberCount int    `json:"maxMemberCount,omitempty"`
+}
+
+// ContactList 联系人列表及其拉取时间
+type ContactList struct {
+	Items     []Contact
+	UpdatedAt time.Time
+}
+
+// Segment 富文本消息段（OneBot11 格式）
+type Segment struct {
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// Target 消息目标，Group 为 true 时表示群聊
+type Target struct {
+	ID    string
+	Group bool
+}
+
+// Message 待发送消息，按 Segments → FilePath → FileURL → Text 的优先级取第一个非空字段
+type Message struct {
+	Text     string
+	Segments []Segment
+	FilePath string // 本地文件绝对路径
+	FileURL  string
+}
+
+// SendResult 发送结果
+type SendResult struct {
+	MessageID string `json:"messageId,omitempty"`
+}
+
+// LoginInfo 登录引导信息
+type LoginInfo struct {
+	QRCode string `json:"qrcode,omitempty"` // data:image/png;base64,...
+	URL    string `json:"url,omitempty"`    // 登录页面地址
+}
+
+// Adapter 聊天通道适配器。新增通道只需实现该接口并通过 Register 注册
+type Adapter interface {
+	ID() string
+	// Status 出错时也返回非 nil 的 Status
+	Status() (*Status, error)
+	Send(target Target, msg Message) (*SendResult, error)
+	ListContacts(kind string, refresh bool) (*ContactList, error)
+	Login() (*LoginInfo, error)
+	Logout() error
+}
+
+var (
+	registry = map[string]Adapter{}
+	regMu    sync.RWMutex
+)
+
+// Register 注册通道适配器，相同 ID 会覆盖
+func Register(a Adapter) {
+	regMu.Lock()
+	defer regMu.Unlock()
+	registry[a.ID()] = a
+}
+
+// Get 按通道 ID 获取适配器
+func Get(id string) (Adapter, bool) {
+	regMu.RLock()
+	defer regMu.RUnlock()
+	a, ok := registry[id]
+	return a, ok
+}
+
+// All 返回全部已注册适配器（按 ID 排序）
+func All() []Adapter {
+	regMu.RLock()
+	defer regMu.RUnlock()
+	list := make([]Adapter, 0, len(registry))
+	for _, a := range registry {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID() < list[j].ID() })
+	return list
+}
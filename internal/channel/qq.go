@@ -0,0 +1,390 @@
+package channel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"github.com/zhaoxinyi02/ClawPanel/internal/config"
+	"github.com/zhaoxinyi02/ClawPanel/internal/napcat"
+	"github.com/zhaoxinyi02/ClawPanel/internal/onebot"
+)
+
+// contactTTL 群/好友列表缓存有效期
+const contactTTL = 2 * time.Minute
+
+// QQAdapter 基于 NapCat WebUI + OneBot11 HTTP API 的 QQ 通道，一个适配器对应一个 NapCat 实例
+type QQAdapter struct {
+	cfg        *config.Config
+	instanceID string
+	api        *onebot.Client
+	napcat     *napcat.Client
+
+	// cacheMu 只保护缓存本身，不在网络请求期间持有
+	cacheMu  sync.Mutex
+	contacts map[string]*ContactList
+	inflight map[string]*contactCall
+
+	selfMu sync.RWMutex
+	selfID string
+}
+
+// contactCall 一次进行中的群/好友列表查询，同类并发请求共享结果
+type contactCall struct {
+	done chan struct{}
+	list *ContactList
+	err  error
+}
+
+// NewQQAdapter 创建 QQ 通道适配器，instanceID 为空时使用第一个 NapCat 实例
+func NewQQAdapter(cfg *config.Config, instanceID string) *QQAdapter {
+	q := &QQAdapter{
+		cfg:        cfg,
+		instanceID: instanceID,
+		contacts:   map[string]*ContactList{},
+		inflight:   map[string]*contactCall{},
+	}
+	q.api = onebot.NewClient(
+		func() string { return q.Instance().OneBotHTTP },
+		func() string { return cfg.OneBotAccessToken(q.instanceID) },
+	)
+	q.napcat = napcat.NewClient(func() string { return q.Instance().WebUI }, q.webuiToken)
+	return q
+}
+
+var (
+	qqInstances []*QQAdapter
+	qqMu        sync.RWMutex
+)
+
+// LoadQQInstances 为每个 NapCat 实例创建适配器，第一个实例注册为 "qq" 通道
+func LoadQQInstances(cfg *config.Config) []*QQAdapter {
+	list := []*QQAdapter{}
+	for _, inst := range cfg.GetNapcatInstances() {
+		list = append(list, NewQQAdapter(cfg, inst.ID))
+	}
+	qqMu.Lock()
+	qqInstances = list
+	qqMu.Unlock()
+	Register(list[0])
+	return list
+}
+
+// QQInstances 返回全部 QQ 实例适配器
+func QQInstances() []*QQAdapter {
+	qqMu.RLock()
+	defer qqMu.RUnlock()
+	return append([]*QQAdapter(nil), qqInstances...)
+}
+
+// QQInstance 按实例 ID 获取 QQ 适配器，id 为空时返回第一个实例
+func QQInstance(id string) (*QQAdapter, bool) {
+	qqMu.RLock()
+	defer qqMu.RUnlock()
+	for _, q := range qqInstances {
+		if id == "" || q.instanceID == id {
+			return q, true
+		}
+	}
+	return nil, false
+}
+
+// QQInstanceBySelfID 按 QQ 号查找实例。未找到时向尚未确定 QQ 号的实例查询一次登录信息后重试
+func QQInstanceBySelfID(selfID string) (*QQAdapter, bool) {
+	if selfID == "" {
+		return nil, false
+	}
+	instances := QQInstances()
+	for _, q := range instances {
+		if q.SelfID() == selfID {
+			return q, true
+		}
+	}
+	for _, q := range instances {
+		if q.SelfID() == "" && q.ResolveSelfID() == selfID {
+			return q, true
+		}
+	}
+	return nil, false
+}
+
+// InstanceID NapCat 实例 ID
+func (q *QQAdapter) InstanceID() string { return q.instanceID }
+
+// Instance 当前实例配置，地址变更即时生效
+func (q *QQAdapter) Instance() config.NapcatInstance {
+	inst, _ := q.cfg.GetNapcatInstance(q.instanceID)
+	return inst
+}
+
+// SelfID 最近一次查询到的登录 QQ 号
+func (q *QQAdapter) SelfID() string {
+	q.selfMu.RLock()
+	defer q.selfMu.RUnlock()
+	return q.selfID
+}
+
+// SetSelfID 记录实例登录的 QQ 号（来自事件 self_id 或登录信息）
+func (q *QQAdapter) SetSelfID(selfID string) {
+	if selfID == "" {
+		return
+	}
+	q.selfMu.Lock()
+	q.selfID = selfID
+	q.selfMu.Unlock()
+}
+
+// ResolveSelfID 通过 OneBot11 get_login_info 查询登录的 QQ 号，失败时返回已知值
+func (q *QQAdapter) ResolveSelfID() string {
+	var info struct {
+		UserID json.Number `json:"user_id"`
+	}
+	if err := q.api.Call("get_login_info", nil, &info); err == nil {
+		q.SetSelfID(info.UserID.String())
+	}
+	return q.SelfID()
+}
+
+// Reload 服务地址变更后清空 NapCat 凭证和联系人缓存
+func (q *QQAdapter) Reload() {
+	q.napcat.Invalidate("")
+	q.InvalidateContacts()
+}
+
+// ID 通道 ID
+func (q *QQAdapter) ID() string { return "qq" }
+
+// OneBot 返回 OneBot11 HTTP API 客户端
+func (q *QQAdapter) OneBot() *onebot.Client { return q.api }
+
+// Status 查询 NapCat 登录状态、账号信息及群/好友数量
+func (q *QQAdapter) Status() (*Status, error) {
+	st := &Status{Extra: map[string]interface{}{}}
+	loginR, err := q.napcatCall("POST", "/api/QQLogin/CheckLoginStatus", nil, 3*time.Second)
+	if err != nil {
+		return st, err
+	}
+	st.Connected = true
+	data, _ := loginR["data"].(map[string]interface{})
+	if isLogin, _ := data["isLogin"].(bool); !isLogin {
+		return st, nil
+	}
+	st.LoggedIn = true
+	if infoR, err := q.napcatCall("POST", "/api/QQLogin/GetQQLoginInfo", nil, 3*time.Second); err == nil {
+		if infoData, ok := infoR["data"].(map[string]interface{}); ok {
+			st.Nickname, _ = infoData["nick"].(string)
+			st.SelfID, _ = infoData["uin"].(string)
+			q.SetSelfID(st.SelfID)
+		}
+	}
+	if groups, err := q.ListContacts("group", false); err == nil {
+		st.Extra["groupCount"] = len(groups.Items)
+	}
+	if friends, err := q.ListContacts("friend", false); err == nil {
+		st.Extra["friendCount"] = len(friends.Items)
+	}
+	return st, nil
+}
+
+// ListContacts 获取群（kind=group）或好友（kind=friend）列表，结果缓存 contactTTL。
+// 同类并发请求只查询一次 OneBot
+func (q *QQAdapter) ListContacts(kind string, refresh bool) (*ContactList, error) {
+	if kind != "group" && kind != "friend" {
+		return nil, fmt.Errorf("unknown contact kind: %s", kind)
+	}
+	q.cacheMu.Lock()
+	if cached := q.contacts[kind]; cached != nil && !refresh && time.Since(cached.UpdatedAt) < contactTTL {
+		q.cacheMu.Unlock()
+		return cached, nil
+	}
+	if call := q.inflight[kind]; call != nil {
+		q.cacheMu.Unlock()
+		<-call.done
+		return call.list, call.err
+	}
+	call := &contactCall{done: make(chan struct{})}
+	q.inflight[kind] = call
+	q.cacheMu.Unlock()
+
+	call.list, call.err = q.fetchContacts(kind, refresh)
+
+	q.cacheMu.Lock()
+	// 查询期间缓存被清空时不写回旧结果
+	if q.inflight[kind] == call {
+		delete(q.inflight, kind)
+		if call.err == nil {
+			q.contacts[kind] = call.list
+		}
+	}
+	q.cacheMu.Unlock()
+	close(call.done)
+	return call.list, call.err
+}
+
+// fetchContacts 通过 OneBot11 get_group_list / get_friend_list 查询列表
+func (q *QQAdapter) fetchContacts(kind string, refresh bool) (*ContactList, error) {
+	params := map[string]interface{}{"no_cache": refresh}
+	items := []Contact{}
+	if kind == "group" {
+		var raw []struct {
+			GroupID        int64  `json:"group_id"`
+			GroupName      string `json:"group_name"`
+			MemberCount    int    `json:"member_count"`
+			MaxMemberCount int    `json:"max_member_count"`
+		}
+		if err := q.api.Call("get_group_list", params, &raw); err != nil {
+			return nil, err
+		}
+		for _, g := range raw {
+			items = append(items, Contact{
+				ID:             strconv.FormatInt(g.GroupID, 10),
+				Kind:           "group",
+				Name:           g.GroupName,
+				MemberCount:    g.MemberCount,
+				MaxMemberCount: g.MaxMemberCount,
+			})
+		}
+	} else {
+		var raw []struct {
+			UserID   int64  `json:"user_id"`
+			Nickname string `json:"nickname"`
+			Remark   string `json:"remark"`
+		}
+		if err := q.api.Call("get_friend_list", params, &raw); err != nil {
+			return nil, err
+		}
+		for _, f := range raw {
+			items = append(items, Contact{
+				ID:     strconv.FormatInt(f.UserID, 10),
+				Kind:   "friend",
+				Name:   f.Nickname,
+				Remark: f.Remark,
+			})
+		}
+	}
+	return &ContactList{Items: items, UpdatedAt: time.Now()}, nil
+}
+
+// InvalidateContacts 清空群/好友缓存
+func (q *QQAdapter) InvalidateContacts() {
+	q.cacheMu.Lock()
+	q.contacts = map[string]*ContactList{}
+	q.inflight = map[string]*contactCall{}
+	q.cacheMu.Unlock()
+}
+
+// Send 发送私聊或群消息，返回 OneBot message_id
+func (q *QQAdapter) Send(target Target, msg Message) (*SendResult, error) {
+	id, err := strconv.ParseInt(target.ID, 10, 64)
+	if err != nil || id <= 0 {
+		return nil, fmt.Errorf("invalid target id: %s", target.ID)
+	}
+	segs := msg.Segments
+	switch {
+	case len(segs) > 0:
+	case msg.FilePath != "":
+		data, err := os.ReadFile(msg.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		segs = []Segment{{Type: "file", Data: map[string]interface{}{
+			"file": "base64://" + base64.StdEncoding.EncodeToString(data),
+			"name": filepath.Base(msg.FilePath),
+		}}}
+	case msg.FileURL != "":
+		segs = []Segment{{Type: "file", Data: map[string]interface{}{"file": msg.FileURL}}}
+	case msg.Text != "":
+		segs = []Segment{{Type: "text", Data: map[string]interface{}{"text": msg.Text}}}
+	default:
+		return nil, fmt.Errorf("message required")
+	}
+
+	params := map[string]interface{}{"message": segs}
+	action := "send_private_msg"
+	if target.Group {
+		action = "send_group_msg"
+		params["group_id"] = id
+	} else {
+		params["user_id"] = id
+	}
+	var result struct {
+		MessageID int64 `json:"message_id"`
+	}
+	if err := q.api.Call(action, params, &result); err != nil {
+		return nil, err
+	}
+	return &SendResult{MessageID: strconv.FormatInt(result.MessageID, 10)}, nil
+}
+
+// Login 获取扫码登录二维码
+func (q *QQAdapter) Login() (*LoginInfo, error) {
+	r, err := q.NapcatCall("POST", "/api/QQLogin/GetQQLoginQrcode", nil)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := r["data"].(map[string]interface{})
+	qr, _ := data["qrcode"].(string)
+	if qr == "" {
+		msg, _ := r["message"].(string)
+		return nil, fmt.Errorf("获取二维码失败: %s", msg)
+	}
+	return &LoginInfo{QRCode: QRCodeDataURL(qr)}, nil
+}
+
+// Logout 通过重启 NapCat 容器退出登录
+func (q *QQAdapter) Logout() error {
+	return q.Restart()
+}
+
+// Restart 异步重启 NapCat 容器
+func (q *QQAdapter) Restart() error {
+	go func() {
+		exec.Command("docker", "restart", q.Instance().Container).Run()
+	}()
+	return nil
+}
+
+// NapcatCall 调用 NapCat WebUI API，凭证失效时自动重新登录一次
+func (q *QQAdapter) NapcatCall(method, path string, body interface{}) (map[string]interface{}, error) {
+	return q.napcatCall(method, path, body, 15*time.Second)
+}
+
+func (q *QQAdapter) napcatCall(method, path string, body interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	return q.napcat.Call(method, path, body, timeout)
+}
+
+// webuiToken WebUI 登录令牌，优先级：实例配置 > WEBUI_TOKEN 环境变量 > admin-config 中的 napcat.webuiToken
+func (q *QQAdapter) webuiToken() string {
+	if t := q.Instance().Token; t != "" {
+		return t
+	}
+	if envToken := os.Getenv("WEBUI_TOKEN"); envToken != "" {
+		return envToken
+	}
+	if nc, ok := q.cfg.ReadAdminConfig()["napcat"].(map[string]interface{}); ok {
+		if t, ok := nc["webuiToken"].(string); ok && t != "" {
+			return t
+		}
+	}
+	return "openclaw-qq-admin"
+}
+
+// QRCodeDataURL 将 NapCat 返回的二维码 URL 转为 base64 PNG，已是 data URL 时原样返回
+func QRCodeDataURL(qrURL string) string {
+	if qrURL == "" || strings.HasPrefix(qrURL, "data:") {
+		return qrURL
+	}
+	png, err := qrcode.Encode(qrURL, qrcode.Medium, 256)
+	if err != nil {
+		return qrURL
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+}
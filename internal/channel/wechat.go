@@ -0,0 +1,88 @@
+package channel
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/config"
+	"github.com/zhaoxinyi02/ClawPanel/internal/wechat"
+)
+
+// WechatAdapter 基于 wechatbot-webhook 的微信通道
+type WechatAdapter struct {
+	cfg *config.Config
+}
+
+// NewWechatAdapter 创建微信通道适配器
+func NewWechatAdapter(cfg *config.Config) *WechatAdapter {
+	return &WechatAdapter{cfg: cfg}
+}
+
+// ID 通道 ID
+func (w *WechatAdapter) ID() string { return "wechat" }
+
+// Client 创建桥接客户端，地址取自 config.Endpoints.WechatAPI（admin-config 中的 wechat.apiUrl 优先），令牌取自 wechat.token
+func (w *WechatAdapter) Client(timeout time.Duration) *wechat.Client {
+	apiURL, token := w.cfg.GetEndpoints().WechatAPI, ""
+	if wc, ok := w.cfg.ReadAdminConfig()["wechat"].(map[string]interface{}); ok {
+		if u, _ := wc["apiUrl"].(string); u != "" {
+			apiURL = u
+		}
+		token, _ = wc["token"].(string)
+	}
+	return wechat.NewClient(apiURL, token, timeout)
+}
+
+// Status 查询微信登录状态
+func (w *WechatAdapter) Status() (*Status, error) {
+	st, err := w.Client(3 * time.Second).Status()
+	if err != nil {
+		return &Status{}, err
+	}
+	return &Status{Connected: st.Connected, LoggedIn: st.LoggedIn, Nickname: st.Name}, nil
+}
+
+// Send 发送文本或文件，Group 对应微信群聊
+func (w *WechatAdapter) Send(target Target, msg Message) (*SendResult, error) {
+	if target.ID == "" {
+		return nil, fmt.Errorf("target required")
+	}
+	switch {
+	case len(msg.Segments) > 0:
+		return nil, ErrUnsupported
+	case msg.FilePath != "":
+		f, err := os.Open(msg.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return &SendResult{}, w.Client(60*time.Second).SendFile(target.ID, target.Group, filepath.Base(msg.FilePath), f)
+	case msg.FileURL != "":
+		return &SendResult{}, w.Client(60*time.Second).SendFileURL(target.ID, target.Group, msg.FileURL)
+	case msg.Text != "":
+		return &SendResult{}, w.Client(15*time.Second).SendText(target.ID, target.Group, msg.Text)
+	}
+	return nil, fmt.Errorf("message required")
+}
+
+// ListContacts wechatbot-webhook 不提供联系人列表
+func (w *WechatAdapter) ListContacts(kind string, refresh bool) (*ContactList, error) {
+	return nil, ErrUnsupported
+}
+
+// Login 返回扫码登录页地址。先确认桥接服务可达且令牌有效，避免返回打不开的登录页
+func (w *WechatAdapter) Login() (*LoginInfo, error) {
+	client := w.Client(5 * time.Second)
+	if _, err := client.Status(); err != nil {
+		return nil, err
+	}
+	return &LoginInfo{URL: client.BaseURL + "/login?token=" + url.QueryEscape(client.Token)}, nil
+}
+
+// Logout wechatbot-webhook 不支持主动退出
+func (w *WechatAdapter) Logout() error {
+	return ErrUnsupported
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 )
 
@@ -19,9 +20,54 @@ type Config struct {
 	JWTSecret   string `json:"jwtSecret"`
 	AdminToken  string `json:"adminToken"`
 	Debug       bool   `json:"debug"`
+	Endpoints
+	NapcatInstances []NapcatInstance `json:"napcatInstances,omitempty"`
+	OneBotReverse   OneBotReverse    `json:"onebotReverse"`
+	// AllowedOrigins 允许连接 WebSocket 的跨域来源（如 http://localhost:5173），同源请求总是允许
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
 	mu          sync.RWMutex
 }
 
+// NapcatInstance 一个 NapCat 实例（一个 QQ 账号），留空的地址/容器名取 Endpoints 中的值
+type NapcatInstance struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	WebUI      string `json:"webui"`
+	Token      string `json:"token"` // WebUI 登录令牌，留空使用 admin-config 中的 napcat.webuiToken
+	Container  string `json:"container"`
+	OneBotHTTP string `json:"onebotHttp"`
+	OneBotWS   string `json:"onebotWs"`
+	// OneBot11 access_token，留空依次取 NAPCAT_TOKEN 环境变量、openclaw.json 的 channels.qq.accessToken、admin-config 的 napcat.accessToken
+	AccessToken string `json:"accessToken"`
+}
+
+// OneBotReverse OneBot11 反向 WebSocket 服务端配置，NapCat 主动连接 ClawPanel 的 /onebot/v11/ws
+type OneBotReverse struct {
+	Enabled        bool   `json:"enabled"`
+	AccessToken    string `json:"accessToken"`
+	DisableForward bool   `json:"disableForward"` // 仅使用反向 WS 时不再主动连接 NapCat
+}
+
+// Endpoints NapCat / OneBot / 微信服务地址与 Docker 容器名
+type Endpoints struct {
+	NapcatWebUI     string `json:"napcatWebUI"`
+	OneBotHTTP      string `json:"onebotHttp"`
+	OneBotWS        string `json:"onebotWs"`
+	WechatAPI       string `json:"wechatApi"`
+	QQContainer     string `json:"qqContainer"`
+	WechatContainer string `json:"wechatContainer"`
+}
+
+// DefaultEndpoints 默认本机部署的服务地址
+var DefaultEndpoints = Endpoints{
+	NapcatWebUI:     "http://127.0.0.1:6099",
+	OneBotHTTP:      "http://127.0.0.1:3000",
+	OneBotWS:        "ws://127.0.0.1:3001",
+	WechatAPI:       "http://127.0.0.1:3002",
+	QQContainer:     "openclaw-qq",
+	WechatContainer: "openclaw-wechat",
+}
+
 const (
 	DefaultPort     = 19527
 	ConfigFileName  = "clawpanel.json"
@@ -44,6 +90,7 @@ func Load() (*Config, error) {
 		JWTSecret:   DefaultJWTSecret,
 		AdminToken:  DefaultAdminToken,
 		Debug:       false,
+		Endpoints:   DefaultEndpoints,
 	}
 
 	// 从环境变量覆盖
@@ -82,6 +129,10 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// 服务地址：环境变量优先于配置文件
+	cfg.Endpoints = cfg.Endpoints.withEnv().withDefaults()
+	cfg.NapcatInstances = validNapcatInstances(cfg.NapcatInstances)
+
 	// 设置默认工作目录（基于 OpenClawDir 的父目录）
 	parentDir := filepath.Dir(cfg.OpenClawDir) // e.g. /home/user/openclaw
 	if cfg.OpenClawWork == "" || !dirExists(cfg.OpenClawWork) {
@@ -126,6 +177,186 @@ func (c *Config) GetAdminToken() string {
 	return c.AdminToken
 }
 
+// GetEndpoints 获取当前服务地址
+func (c *Config) GetEndpoints() Endpoints {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Endpoints
+}
+
+// SetEndpoints 修改服务地址并保存，空字段使用默认值
+func (c *Config) SetEndpoints(e Endpoints) error {
+	c.mu.Lock()
+	c.Endpoints = e.withDefaults()
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetOneBotReverse 获取反向 WebSocket 配置，ONEBOT_REVERSE_TOKEN 环境变量优先
+func (c *Config) GetOneBotReverse() OneBotReverse {
+	c.mu.RLock()
+	r := c.OneBotReverse
+	c.mu.RUnlock()
+	if v := os.Getenv("ONEBOT_REVERSE_TOKEN"); v != "" {
+		r.AccessToken = v
+	}
+	return r
+}
+
+// GetAllowedOrigins 获取 WebSocket 跨域来源白名单，CLAWPANEL_ALLOWED_ORIGINS 环境变量（逗号分隔）优先
+func (c *Config) GetAllowedOrigins() []string {
+	if v := os.Getenv("CLAWPANEL_ALLOWED_ORIGINS"); v != "" {
+		var origins []string
+		for _, o := range strings.Split(v, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				origins = append(origins, o)
+			}
+		}
+		return origins
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.AllowedOrigins...)
+}
+
+// DefaultNapcatInstance 未配置 napcatInstances 时使用的实例 ID
+const DefaultNapcatInstance = "default"
+
+// GetNapcatInstances 获取 NapCat 实例列表，未配置时返回由 Endpoints 构成的单个默认实例
+func (c *Config) GetNapcatInstances() []NapcatInstance {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.NapcatInstances) == 0 {
+		return []NapcatInstance{c.Endpoints.napcatInstance(NapcatInstance{ID: DefaultNapcatInstance, Name: "QQ"})}
+	}
+	list := make([]NapcatInstance, 0, len(c.NapcatInstances))
+	for _, inst := range c.NapcatInstances {
+		list = append(list, c.Endpoints.napcatInstance(inst))
+	}
+	return list
+}
+
+// GetNapcatInstance 按 ID 获取 NapCat 实例，id 为空时返回第一个实例
+func (c *Config) GetNapcatInstance(id string) (NapcatInstance, bool) {
+	list := c.GetNapcatInstances()
+	if id == "" {
+		return list[0], true
+	}
+	for _, inst := range list {
+		if inst.ID == id {
+			return inst, true
+		}
+	}
+	return NapcatInstance{}, false
+}
+
+// OneBotAccessToken 获取 NapCat 实例的 OneBot11 access_token，未配置时返回空
+func (c *Config) OneBotAccessToken(instanceID string) string {
+	if inst, ok := c.GetNapcatInstance(instanceID); ok && inst.AccessToken != "" {
+		return inst.AccessToken
+	}
+	if v := os.Getenv("NAPCAT_TOKEN"); v != "" {
+		return v
+	}
+	if oc, _ := c.ReadOpenClawJSON(); oc != nil {
+		if channels, ok := oc["channels"].(map[string]interface{}); ok {
+			if qq, ok := channels["qq"].(map[string]interface{}); ok {
+				if t, _ := qq["accessToken"].(string); t != "" {
+					return t
+				}
+			}
+		}
+	}
+	if napcat, ok := c.ReadAdminConfig()["napcat"].(map[string]interface{}); ok {
+		if t, _ := napcat["accessToken"].(string); t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// validNapcatInstances 丢弃缺少 ID 或 ID 重复的实例
+func validNapcatInstances(list []NapcatInstance) []NapcatInstance {
+	seen := map[string]bool{}
+	valid := []NapcatInstance{}
+	for _, inst := range list {
+		if inst.ID == "" || seen[inst.ID] {
+			fmt.Printf("[ClawPanel] 忽略无效的 NapCat 实例配置: id=%q\n", inst.ID)
+			continue
+		}
+		seen[inst.ID] = true
+		valid = append(valid, inst)
+	}
+	return valid
+}
+
+// napcatInstance 用服务地址补全实例的空字段
+func (e Endpoints) napcatInstance(inst NapcatInstance) NapcatInstance {
+	if inst.Name == "" {
+		inst.Name = inst.ID
+	}
+	if inst.WebUI == "" {
+		inst.WebUI = e.NapcatWebUI
+	}
+	if inst.Container == "" {
+		inst.Container = e.QQContainer
+	}
+	if inst.OneBotHTTP == "" {
+		inst.OneBotHTTP = e.OneBotHTTP
+	}
+	if inst.OneBotWS == "" {
+		inst.OneBotWS = e.OneBotWS
+	}
+	return inst
+}
+
+// withEnv 用环境变量覆盖服务地址
+func (e Endpoints) withEnv() Endpoints {
+	if v := os.Getenv("NAPCAT_WEBUI_URL"); v != "" {
+		e.NapcatWebUI = v
+	}
+	if v := os.Getenv("ONEBOT_HTTP_URL"); v != "" {
+		e.OneBotHTTP = v
+	}
+	if v := os.Getenv("ONEBOT_WS_URL"); v != "" {
+		e.OneBotWS = v
+	}
+	if v := os.Getenv("WECHAT_API_URL"); v != "" {
+		e.WechatAPI = v
+	}
+	if v := os.Getenv("NAPCAT_CONTAINER"); v != "" {
+		e.QQContainer = v
+	}
+	if v := os.Getenv("WECHAT_CONTAINER"); v != "" {
+		e.WechatContainer = v
+	}
+	return e
+}
+
+// withDefaults 为空字段填充默认值
+func (e Endpoints) withDefaults() Endpoints {
+	d := DefaultEndpoints
+	if e.NapcatWebUI == "" {
+		e.NapcatWebUI = d.NapcatWebUI
+	}
+	if e.OneBotHTTP == "" {
+		e.OneBotHTTP = d.OneBotHTTP
+	}
+	if e.OneBotWS == "" {
+		e.OneBotWS = d.OneBotWS
+	}
+	if e.WechatAPI == "" {
+		e.WechatAPI = d.WechatAPI
+	}
+	if e.QQContainer == "" {
+		e.QQContainer = d.QQContainer
+	}
+	if e.WechatContainer == "" {
+		e.WechatContainer = d.WechatContainer
+	}
+	return e
+}
+
 // getDataDir 获取数据目录（与可执行文件同目录）
 func getDataDir() string {
 	if v := os.Getenv("CLAWPANEL_DATA"); v != "" {
@@ -194,3 +425,13 @@ func (c *Config) OpenClawConfigExists() bool {
 	_, err := os.Stat(cfgPath)
 	return err == nil
 }
+
+// ReadAdminConfig 读取 admin-config.json，不存在或解析失败时返回空 map
+func (c *Config) ReadAdminConfig() map[string]interface{} {
+	result := map[string]interface{}{}
+	data, err := os.ReadFile(filepath.Join(c.DataDir, "admin-config.json"))
+	if err == nil {
+		json.Unmarshal(data, &result)
+	}
+	return result
+}
@@ -0,0 +1,210 @@
+package eventlog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// compiledRule 预处理后的告警规则
+type compiledRule struct {
+	model.AlertRule
+	keywords []string // 小写关键词
+	re       *regexp.Regexp
+	users    map[string]bool
+	groups   map[string]bool
+}
+
+// alertRules 所有监听器共享的规则缓存，规则增删改后调用 ReloadAlertRules 刷新
+var alertRules struct {
+	mu     sync.RWMutex
+	loaded bool
+	rules  []compiledRule
+}
+
+// AlertMessage 告警命中的消息上下文，作为告警事件的 detail 与 WS 推送内容
+type AlertMessage struct {
+	RuleID      int64  `json:"ruleId"`
+	RuleName    string `json:"ruleName"`
+	Instance    string `json:"instance,omitempty"`
+	SelfID      string `json:"selfId"`
+	MessageType string `json:"messageType"`
+	MessageID   string `json:"messageId,omitempty"`
+	GroupID     string `json:"groupId,omitempty"`
+	UserID      string `json:"userId"`
+	Sender      string `json:"sender,omitempty"`
+	Text        string `json:"text"`
+	Matched     string `json:"matched,omitempty"` // 命中的关键词或正则片段
+}
+
+// ReloadAlertRules 从数据库重新加载告警规则
+func ReloadAlertRules(db *sql.DB) error {
+	rules, err := model.GetAlertRules(db)
+	if err != nil {
+		return err
+	}
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		cr := compiledRule{AlertRule: r, users: toSet(r.UserIDs), groups: toSet(r.GroupIDs)}
+		for _, kw := range r.Keywords {
+			if kw = strings.TrimSpace(kw); kw != "" {
+				cr.keywords = append(cr.keywords, strings.ToLower(kw))
+			}
+		}
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				log.Printf("[Alert] 规则 %d 正则无效，已跳过: %v", r.ID, err)
+				continue
+			}
+			cr.re = re
+		}
+		compiled = append(compiled, cr)
+	}
+
+	alertRules.mu.Lock()
+	alertRules.rules = compiled
+	alertRules.loaded = true
+	alertRules.mu.Unlock()
+	return nil
+}
+
+func toSet(list []string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range list {
+		if v = strings.TrimSpace(v); v != "" {
+			set[v] = true
+		}
+	}
+	return set
+}
+
+// match 所有已填写的条件同时满足才命中，返回命中的关键词或正则片段
+func (r *compiledRule) match(m *AlertMessage) (string, bool) {
+	if r.MessageType != "" && r.MessageType != m.MessageType {
+		return "", false
+	}
+	if len(r.groups) > 0 && !r.groups[m.GroupID] {
+		return "", false
+	}
+	if len(r.users) > 0 && !r.users[m.UserID] {
+		return "", false
+	}
+	matched := ""
+	if len(r.keywords) > 0 {
+		text := strings.ToLower(m.Text)
+		for _, kw := range r.keywords {
+			if strings.Contains(text, kw) {
+				matched = kw
+				break
+			}
+		}
+		if matched == "" {
+			return "", false
+		}
+	}
+	if r.re != nil {
+		found := r.re.FindString(m.Text)
+		if found == "" && !r.re.MatchString(m.Text) {
+			return "", false
+		}
+		if matched == "" {
+			matched = found
+		}
+	}
+	return matched, true
+}
+
+// checkAlerts 对收到的消息执行告警规则，命中的每条规则生成一条高级别事件
+func (l *Listener) checkAlerts(msg map[string]interface{}, text string) {
+	alertRules.mu.RLock()
+	loaded := alertRules.loaded
+	alertRules.mu.RUnlock()
+	if !loaded {
+		if err := ReloadAlertRules(l.db); err != nil {
+			log.Printf("[Alert] 加载告警规则失败: %v", err)
+			return
+		}
+	}
+
+	alertRules.mu.RLock()
+	rules := alertRules.rules
+	alertRules.mu.RUnlock()
+	if len(rules) == 0 {
+		return
+	}
+
+	base := AlertMessage{
+		Instance:  l.instance,
+		SelfID:    idString(msg["self_id"]),
+		MessageID: idString(msg["message_id"]),
+		UserID:    idString(msg["user_id"]),
+		Text:      text,
+	}
+	base.MessageType, _ = msg["message_type"].(string)
+	if base.MessageType == "group" {
+		base.GroupID = idString(msg["group_id"])
+	}
+	// 自己发出的消息不触发告警
+	if base.UserID != "" && base.UserID == base.SelfID {
+		return
+	}
+	if s, ok := msg["sender"].(map[string]interface{}); ok {
+		card, _ := s["card"].(string)
+		nickname, _ := s["nickname"].(string)
+		base.Sender = firstNonEmpty(card, nickname)
+	}
+
+	for i := range rules {
+		matched, ok := rules[i].match(&base)
+		if !ok {
+			continue
+		}
+		am := base
+		am.RuleID, am.RuleName, am.Matched = rules[i].ID, rules[i].Name, matched
+		l.raiseAlert(&am, &rules[i].AlertRule)
+	}
+}
+
+// raiseAlert 记录告警事件，以 log-entry 和 alert 两种消息推送，并按需投递到规则单独配置的 Webhook 地址
+func (l *Listener) raiseAlert(am *AlertMessage, rule *model.AlertRule) {
+	where := "[私聊]"
+	if am.MessageType == "group" {
+		where = fmt.Sprintf("[群%s]", am.GroupID)
+	}
+	detail, _ := json.Marshal(am)
+	event := &model.Event{
+		Time:     time.Now().UnixMilli(),
+		Source:   "alert",
+		Type:     "alert.message",
+		Summary:  fmt.Sprintf("%s[告警:%s] %s %s: %s", l.tag(), am.RuleName, where, firstNonEmpty(am.Sender, am.UserID), truncate(am.Text, 80)),
+		Detail:   string(detail),
+		Severity: model.SeverityHigh,
+	}
+	if err := l.pub.Publish(event); err != nil {
+		log.Printf("[Alert] 保存告警事件失败: %v", err)
+		return
+	}
+
+	l.pub.Broadcast(event.Source, "alert", map[string]interface{}{
+		"id":       event.ID,
+		"time":     event.Time,
+		"severity": event.Severity,
+		"summary":  event.Summary,
+		"alert":    am,
+	})
+
+	if rule.WebhookURL != "" {
+		l.pub.DispatchTo(rule.WebhookURL, rule.WebhookSecret, event)
+	}
+}
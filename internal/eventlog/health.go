@@ -0,0 +1,194 @@
+package eventlog
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// 重连退避与心跳检测参数
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// staleFactor 超过心跳间隔的倍数未收到心跳即判定连接失活
+	staleFactor = 3
+	// watchdogTick 失活检查周期
+	watchdogTick = 5 * time.Second
+)
+
+// 连接状态
+const (
+	StateIdle         = "idle" // 未启动（如仅使用反向 WS）
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateStale        = "stale" // 连接未断开但心跳停止
+	StateDisconnected = "disconnected"
+	StateStopped      = "stopped"
+)
+
+// ConnectionState OneBot11 WebSocket 连接健康状态，时间均为毫秒时间戳
+type ConnectionState struct {
+	Instance          string `json:"instance"`
+	URL               string `json:"url"`
+	State             string `json:"state"`
+	ConnectedSince    int64  `json:"connectedSince,omitempty"`
+	LastHeartbeat     int64  `json:"lastHeartbeat,omitempty"`
+	HeartbeatInterval int64  `json:"heartbeatInterval,omitempty"` // NapCat 上报的心跳间隔 (ms)
+	BotOnline         *bool  `json:"botOnline,omitempty"`         // 心跳中的 status.online
+	LastEventAt       int64  `json:"lastEventAt,omitempty"`
+	ReconnectCount    int    `json:"reconnectCount"`
+	LastError         string `json:"lastError,omitempty"`
+	LastErrorAt       int64  `json:"lastErrorAt,omitempty"`
+	NextRetryAt       int64  `json:"nextRetryAt,omitempty"`
+}
+
+// health 连接健康数据，由 Listener.mu 保护
+type health struct {
+	state          string
+	connectedSince time.Time
+	lastHeartbeat  time.Time
+	hbInterval     time.Duration
+	botOnline      *bool
+	lastEventAt    time.Time
+	attempt        int // 连续失败次数，连接成功后清零
+	reconnects     int
+	lastErr        string
+	lastErrAt      time.Time
+	nextRetry      time.Time
+}
+
+// State 返回当前连接状态快照
+func (l *Listener) State() ConnectionState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.health
+	st := ConnectionState{
+		Instance:          l.instance,
+		URL:               l.wsURL,
+		State:             h.state,
+		HeartbeatInterval: h.hbInterval.Milliseconds(),
+		BotOnline:         h.botOnline,
+		ReconnectCount:    h.reconnects,
+		LastError:         h.lastErr,
+	}
+	if st.State == "" {
+		st.State = StateIdle
+	}
+	st.ConnectedSince = unixMilli(h.connectedSince)
+	st.LastHeartbeat = unixMilli(h.lastHeartbeat)
+	st.LastEventAt = unixMilli(h.lastEventAt)
+	st.LastErrorAt = unixMilli(h.lastErrAt)
+	st.NextRetryAt = unixMilli(h.nextRetry)
+	return st
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func (l *Listener) setState(state string) {
+	l.mu.Lock()
+	l.health.state = state
+	if state == StateConnected {
+		l.health.connectedSince = time.Now()
+		l.health.attempt = 0
+		l.health.nextRetry = time.Time{}
+	} else if state != StateStale {
+		l.health.connectedSince = time.Time{}
+	}
+	l.mu.Unlock()
+}
+
+// scheduleRetry 记录错误并返回带抖动的指数退避时间：1s, 2s, 4s ... 最长 1 分钟，实际取 [d/2, d)
+func (l *Listener) scheduleRetry(err error) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		l.health.lastErr = err.Error()
+		l.health.lastErrAt = time.Now()
+	}
+	d := minBackoff << uint(l.health.attempt)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	} else {
+		l.health.attempt++
+	}
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)))
+	l.health.reconnects++
+	l.health.state = StateDisconnected
+	l.health.connectedSince = time.Time{}
+	l.health.nextRetry = time.Now().Add(d)
+	return d
+}
+
+// recordMeta 处理元事件，记录心跳时间、间隔和机器人在线状态
+func (l *Listener) recordMeta(msg map[string]interface{}) {
+	if t, _ := msg["meta_event_type"].(string); t != "heartbeat" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.health.lastHeartbeat = time.Now()
+	if interval, ok := msg["interval"].(float64); ok && interval > 0 {
+		l.health.hbInterval = time.Duration(interval) * time.Millisecond
+	}
+	if status, ok := msg["status"].(map[string]interface{}); ok {
+		if online, ok := status["online"].(bool); ok {
+			l.health.botOnline = &online
+		}
+	}
+	// 心跳恢复
+	if l.health.state == StateStale {
+		l.health.state = StateConnected
+	}
+}
+
+// recordEvent 记录最近一次收到事件的时间
+func (l *Listener) recordEvent() {
+	l.mu.Lock()
+	l.health.lastEventAt = time.Now()
+	l.mu.Unlock()
+}
+
+// watchdog 心跳超过 staleFactor 个间隔未到达时判定连接失活，断开后由 connectLoop 重连。done 关闭时退出
+func (l *Listener) watchdog(done <-chan struct{}) {
+	ticker := time.NewTicker(watchdogTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		l.mu.Lock()
+		h := &l.health
+		if h.state != StateConnected || h.hbInterval <= 0 {
+			l.mu.Unlock()
+			continue
+		}
+		since := h.lastHeartbeat
+		if since.Before(h.connectedSince) {
+			since = h.connectedSince
+		}
+		silent := time.Since(since)
+		if silent <= staleFactor*h.hbInterval {
+			l.mu.Unlock()
+			continue
+		}
+		h.state = StateStale
+		interval := h.hbInterval
+		conn := l.conn
+		l.mu.Unlock()
+
+		msg := fmt.Sprintf("%s 未收到心跳（间隔 %s）", silent.Round(time.Second), interval)
+		log.Printf("[EventLog] OneBot11 连接失活: %s，断开重连", msg)
+		l.sysLog.LogDetail("system", "napcat.stale", l.tag()+"NapCat OneBot11 心跳停止，连接已失活", msg)
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
@@ -5,36 +5,115 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	gorilla "github.com/gorilla/websocket"
 	"github.com/zhaoxinyi02/ClawPanel/internal/model"
-	"github.com/zhaoxinyi02/ClawPanel/internal/websocket"
+	"github.com/zhaoxinyi02/ClawPanel/internal/onebot"
 )
 
 // Listener monitors OneBot11 WebSocket for message events and records them
 type Listener struct {
 	db      *sql.DB
-	hub     *websocket.Hub
+	pub     *Publisher
 	wsURL   string
 	conn    *gorilla.Conn
 	mu      sync.Mutex
 	stopCh  chan struct{}
+	kickCh  chan struct{}
 	running bool
 	sysLog  *SystemLogger
+	health  health
+
+	// OneBot11 access_token，握手时以 Bearer 发送
+	token func() string
+	// 鉴权失败日志节流：ws / http → 上次记录时间
+	authFailedAt map[string]time.Time
+
+	// 所属 NapCat 实例，多账号时显示名会加在事件摘要前
+	instance string
+	name     string
+	// 正向连接收到首个带 self_id 的事件时回调，用于确定实例登录的 QQ 号
+	onSelfID func(selfID string)
+	// 当前推送事件的反向 WS 连接数，大于 0 时正向连接只用于心跳检测，避免同一事件处理两次
+	reverseConns atomic.Int32
+
+	// 请求自动处理策略
+	policyDir string
+	api       *onebot.Client
 }
 
 // NewListener creates a new event listener
-func NewListener(db *sql.DB, hub *websocket.Hub, wsURL string) *Listener {
+func NewListener(db *sql.DB, pub *Publisher, wsURL string) *Listener {
 	return &Listener{
 		db:     db,
-		hub:    hub,
+		pub:    pub,
 		wsURL:  wsURL,
 		stopCh: make(chan struct{}),
-		sysLog: NewSystemLogger(db, hub),
+		kickCh: make(chan struct{}, 1),
+		sysLog: NewSystemLogger(pub),
+
+		authFailedAt: map[string]time.Time{},
+	}
+}
+
+// Listeners NapCat 实例 ID → 事件监听器
+type Listeners map[string]*Listener
+
+// SetInstance 标记监听器所属的 NapCat 实例，name 非空时事件摘要前加 [name]
+func (l *Listener) SetInstance(id, name string) {
+	l.instance = id
+	l.name = name
+}
+
+// OnSelfID 设置正向连接上报登录 QQ 号的回调，每次连接后首个带 self_id 的事件触发一次
+func (l *Listener) OnSelfID(fn func(selfID string)) {
+	l.onSelfID = fn
+}
+
+// SetAccessToken 设置 OneBot11 access_token 来源，每次连接时读取
+func (l *Listener) SetAccessToken(token func() string) {
+	l.token = token
+}
+
+// authLogInterval 同类鉴权失败的最小记录间隔
+const authLogInterval = 5 * time.Minute
+
+// ReportAuthFailure 以 napcat.auth_failed 记录 access_token 鉴权失败，kind 为 ws / http，同类失败 5 分钟内只记一次
+func (l *Listener) ReportAuthFailure(kind string, err error) {
+	l.mu.Lock()
+	last := l.authFailedAt[kind]
+	now := time.Now()
+	if now.Sub(last) < authLogInterval {
+		l.mu.Unlock()
+		return
 	}
+	l.authFailedAt[kind] = now
+	l.mu.Unlock()
+
+	what := "WebSocket"
+	if kind == "http" {
+		what = "HTTP API"
+	}
+	log.Printf("[EventLog] OneBot11 %s 鉴权失败: %v", what, err)
+	l.sysLog.LogDetail("system", "napcat.auth_failed",
+		fmt.Sprintf("%sNapCat OneBot11 %s 鉴权失败，请检查 access_token", l.tag(), what), err.Error())
+}
+
+// Instance 所属 NapCat 实例 ID
+func (l *Listener) Instance() string { return l.instance }
+
+// tag 事件摘要前缀
+func (l *Listener) tag() string {
+	if l.name == "" {
+		return ""
+	}
+	return "[" + l.name + "] "
 }
 
 // Start begins listening for OneBot11 events
@@ -58,12 +137,55 @@ func (l *Listener) Stop() {
 		return
 	}
 	l.running = false
+	l.health.state = StateStopped
 	close(l.stopCh)
 	if l.conn != nil {
 		l.conn.Close()
 	}
 }
 
+// SetURL 修改 OneBot11 WebSocket 地址并立即重连
+func (l *Listener) SetURL(wsURL string) {
+	l.mu.Lock()
+	changed := l.wsURL != wsURL
+	l.wsURL = wsURL
+	l.mu.Unlock()
+	if changed {
+		l.Reconnect()
+	}
+}
+
+// URL 当前 OneBot11 WebSocket 地址
+func (l *Listener) URL() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.wsURL
+}
+
+// Reconnect 断开当前连接并跳过重试等待立即重连
+func (l *Listener) Reconnect() {
+	l.mu.Lock()
+	if l.conn != nil {
+		l.conn.Close()
+	}
+	l.mu.Unlock()
+	select {
+	case l.kickCh <- struct{}{}:
+	default:
+	}
+}
+
+// wait 等待 d 后重试，收到 Stop 返回 false，收到 Reconnect 提前返回
+func (l *Listener) wait(d time.Duration) bool {
+	select {
+	case <-l.stopCh:
+		return false
+	case <-l.kickCh:
+	case <-time.After(d):
+	}
+	return true
+}
+
 func (l *Listener) connectLoop() {
 	for {
 		select {
@@ -72,24 +194,28 @@ func (l *Listener) connectLoop() {
 		default:
 		}
 
+		l.setState(StateConnecting)
 		err := l.connect()
 		if err != nil {
-			log.Printf("[EventLog] OneBot11 连接失败: %v, 10秒后重试", err)
-			select {
-			case <-l.stopCh:
+			d := l.scheduleRetry(err)
+			log.Printf("[EventLog] OneBot11 连接失败: %v, %s后重试", err, d.Round(100*time.Millisecond))
+			if !l.wait(d) {
 				return
-			case <-time.After(10 * time.Second):
 			}
 			continue
 		}
 
 		l.listen()
-		log.Println("[EventLog] OneBot11 连接断开, 5秒后重连")
-		l.sysLog.Log("system", "napcat.disconnected", "NapCat OneBot11 WebSocket 连接断开")
 		select {
 		case <-l.stopCh:
 			return
-		case <-time.After(5 * time.Second):
+		default:
+		}
+		d := l.scheduleRetry(nil)
+		log.Printf("[EventLog] OneBot11 连接断开, %s后重连", d.Round(100*time.Millisecond))
+		l.sysLog.Log("system", "napcat.disconnected", l.tag()+"NapCat OneBot11 WebSocket 连接断开")
+		if !l.wait(d) {
+			return
 		}
 	}
 }
@@ -98,19 +224,35 @@ func (l *Listener) connect() error {
 	dialer := gorilla.Dialer{
 		HandshakeTimeout: 5 * time.Second,
 	}
-	conn, _, err := dialer.Dial(l.wsURL, nil)
+	wsURL := l.URL()
+	header := http.Header{}
+	if l.token != nil {
+		if token := l.token(); token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	conn, resp, err := dialer.Dial(wsURL, header)
 	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) {
+			err = fmt.Errorf("握手被拒绝 (HTTP %d): %w", resp.StatusCode, err)
+			l.ReportAuthFailure("ws", err)
+		}
 		return err
 	}
 	l.mu.Lock()
 	l.conn = conn
+	delete(l.authFailedAt, "ws")
 	l.mu.Unlock()
-	log.Printf("[EventLog] 已连接 OneBot11 WebSocket: %s", l.wsURL)
-	l.sysLog.Log("system", "napcat.connected", "NapCat OneBot11 WebSocket 已连接")
+	l.setState(StateConnected)
+	log.Printf("[EventLog] 已连接 OneBot11 WebSocket: %s", wsURL)
+	l.sysLog.Log("system", "napcat.connected", l.tag()+"NapCat OneBot11 WebSocket 已连接")
 	return nil
 }
 
 func (l *Listener) listen() {
+	done := make(chan struct{})
+	go l.watchdog(done)
+	defer close(done)
 	defer func() {
 		l.mu.Lock()
 		if l.conn != nil {
@@ -120,6 +262,7 @@ func (l *Listener) listen() {
 		l.mu.Unlock()
 	}()
 
+	selfIDKnown := l.onSelfID == nil
 	for {
 		select {
 		case <-l.stopCh:
@@ -132,11 +275,27 @@ func (l *Listener) listen() {
 			return
 		}
 
-		l.processMessage(msg)
+		if !selfIDKnown {
+			selfIDKnown = l.reportSelfID(msg)
+		}
+		l.processMessage(msg, false)
 	}
 }
 
-func (l *Listener) processMessage(raw []byte) {
+// reportSelfID 从事件中读取 self_id 交给 onSelfID，读到时返回 true
+func (l *Listener) reportSelfID(raw []byte) bool {
+	var msg struct {
+		SelfID json.Number `json:"self_id"`
+	}
+	if json.Unmarshal(raw, &msg) != nil || msg.SelfID == "" {
+		return false
+	}
+	l.onSelfID(msg.SelfID.String())
+	return true
+}
+
+// processMessage 处理一条 OneBot11 推送，reverse 表示来自反向 WS 连接
+func (l *Listener) processMessage(raw []byte, reverse bool) {
 	var msg map[string]interface{}
 	if err := json.Unmarshal(raw, &msg); err != nil {
 		return
@@ -144,13 +303,23 @@ func (l *Listener) processMessage(raw []byte) {
 
 	postType, _ := msg["post_type"].(string)
 	if postType == "" {
+		// NapCat 在 token 不匹配时接受握手后返回 retcode 1403 再断开
+		if retcode, _ := msg["retcode"].(float64); retcode == 1403 {
+			message, _ := msg["message"].(string)
+			l.ReportAuthFailure("ws", fmt.Errorf("retcode 1403: %s", message))
+		}
 		return
 	}
 
-	// Skip heartbeat events
+	// 心跳只用于连接健康检测，不写入日志
 	if postType == "meta_event" {
+		l.recordMeta(msg)
+		return
+	}
+	if !reverse && l.reverseConns.Load() > 0 {
 		return
 	}
+	l.recordEvent()
 
 	var event *model.Event
 
@@ -166,29 +335,18 @@ func (l *Listener) processMessage(raw []byte) {
 	if event == nil {
 		return
 	}
+	event.Summary = l.tag() + event.Summary
 
-	// Save to DB
-	id, err := model.AddEvent(l.db, event)
-	if err != nil {
+	// Save to DB and broadcast as log-entry
+	if err := l.pub.Publish(event); err != nil {
 		log.Printf("[EventLog] 保存事件失败: %v", err)
 		return
 	}
-	event.ID = id
-
-	// Broadcast to WebSocket clients as log-entry
-	entry := map[string]interface{}{
-		"id":      event.ID,
-		"time":    event.Time,
-		"source":  event.Source,
-		"type":    event.Type,
-		"summary": event.Summary,
-		"detail":  event.Detail,
-	}
-	wsMsg, _ := json.Marshal(map[string]interface{}{
-		"type": "log-entry",
-		"data": entry,
-	})
-	l.hub.Broadcast(wsMsg)
+
+	// 收到的消息执行告警规则
+	if postType == "message" {
+		l.checkAlerts(msg, event.Detail)
+	}
 }
 
 func (l *Listener) parseMessageEvent(msg map[string]interface{}) *model.Event {
@@ -221,18 +379,19 @@ func (l *Listener) parseMessageEvent(msg map[string]interface{}) *model.Event {
 		}
 	}
 
-	selfID := fmt.Sprintf("%v", msg["self_id"])
-	userID := fmt.Sprintf("%v", msg["user_id"])
-	sender := ""
+	selfID := idString(msg["self_id"])
+	userID := idString(msg["user_id"])
+	sender, nickname, card := "", "", ""
 	if s, ok := msg["sender"].(map[string]interface{}); ok {
-		nickname, _ := s["nickname"].(string)
-		card, _ := s["card"].(string)
+		nickname, _ = s["nickname"].(string)
+		card, _ = s["card"].(string)
 		if card != "" {
 			sender = card
 		} else if nickname != "" {
 			sender = nickname
 		}
 	}
+	l.saveMessage(msg, msgType, selfID, userID, card, nickname, rawMsg)
 
 	source := "qq"
 	isSelf := userID == selfID
@@ -241,7 +400,7 @@ func (l *Listener) parseMessageEvent(msg map[string]interface{}) *model.Event {
 	var eventType string
 
 	if msgType == "group" {
-		groupID := fmt.Sprintf("%v", msg["group_id"])
+		groupID := idString(msg["group_id"])
 		if isSelf {
 			source = "openclaw"
 			eventType = "message.group.sent"
@@ -278,44 +437,193 @@ func (l *Listener) parseMessageEvent(msg map[string]interface{}) *model.Event {
 	}
 }
 
+// parseNoticeEvent 解析通知事件，摘要写明操作者与对象，detail 保存结构化上下文（JSON）
 func (l *Listener) parseNoticeEvent(msg map[string]interface{}) *model.Event {
 	noticeType, _ := msg["notice_type"].(string)
+	subType, _ := msg["sub_type"].(string)
+	selfID := idString(msg["self_id"])
+	userID := idString(msg["user_id"])
+	groupID := idString(msg["group_id"])
+	operatorID := idString(msg["operator_id"])
+
+	eventType := noticeType
 	summary := ""
+	detail := map[string]interface{}{"noticeType": noticeType}
+	if subType != "" {
+		detail["subType"] = subType
+	}
+	if groupID != "" && groupID != "0" {
+		detail["groupId"] = groupID
+	}
+	if userID != "" {
+		detail["userId"] = userID
+	}
+	if operatorID != "" && operatorID != "0" {
+		detail["operatorId"] = operatorID
+	}
 
 	switch noticeType {
 	case "group_increase":
-		userID := fmt.Sprintf("%v", msg["user_id"])
-		groupID := fmt.Sprintf("%v", msg["group_id"])
 		summary = fmt.Sprintf("用户 %s 加入群 %s", userID, groupID)
+		if subType == "invite" && operatorID != "" {
+			summary += fmt.Sprintf("（%s 邀请）", operatorID)
+		} else if subType == "approve" && operatorID != "" && operatorID != "0" {
+			summary += fmt.Sprintf("（%s 同意）", operatorID)
+		}
 	case "group_decrease":
-		userID := fmt.Sprintf("%v", msg["user_id"])
-		groupID := fmt.Sprintf("%v", msg["group_id"])
-		summary = fmt.Sprintf("用户 %s 离开群 %s", userID, groupID)
+		switch subType {
+		case "kick":
+			summary = fmt.Sprintf("用户 %s 被 %s 移出群 %s", userID, operatorID, groupID)
+		case "kick_me":
+			summary = fmt.Sprintf("机器人被 %s 移出群 %s", operatorID, groupID)
+		default:
+			summary = fmt.Sprintf("用户 %s 离开群 %s", userID, groupID)
+		}
 	case "friend_add":
-		userID := fmt.Sprintf("%v", msg["user_id"])
 		summary = fmt.Sprintf("新好友: %s", userID)
-	case "group_recall":
-		summary = "群消息撤回"
-	case "friend_recall":
-		summary = "好友消息撤回"
-	case "poke":
-		summary = "戳一戳"
+	case "group_recall", "friend_recall":
+		messageID := idString(msg["message_id"])
+		detail["messageId"] = messageID
+		who := userID
+		original := ""
+		if m, err := model.GetMessage(l.db, selfID, messageID); err == nil {
+			original = m.Text
+			detail["originalText"] = m.Text
+			detail["originalTime"] = m.Time
+			detail["segments"] = m.Segments
+			if name := firstNonEmpty(m.SenderCard, m.SenderNick); name != "" {
+				who = fmt.Sprintf("%s(%s)", name, userID)
+			}
+		}
+		if noticeType == "group_recall" {
+			if operatorID == "" || operatorID == userID {
+				summary = fmt.Sprintf("[群%s] %s 撤回了一条消息", groupID, who)
+			} else {
+				summary = fmt.Sprintf("[群%s] %s 撤回了 %s 的消息", groupID, operatorID, who)
+			}
+		} else {
+			summary = fmt.Sprintf("[私聊] %s 撤回了一条消息", who)
+		}
+		if original != "" {
+			summary += ": " + truncate(original, 80)
+		} else {
+			summary += fmt.Sprintf(" (message_id %s，原消息未记录)", messageID)
+		}
+	case "notify", "poke":
+		// OneBot11 戳一戳为 notice_type=notify, sub_type=poke；部分实现直接使用 notice_type=poke
+		if noticeType == "notify" && subType != "poke" {
+			eventType = "notify." + subType
+			summary = fmt.Sprintf("通知: %s", subType)
+			break
+		}
+		eventType = "poke"
+		targetID := idString(msg["target_id"])
+		detail["targetId"] = targetID
+		if groupID != "" && groupID != "0" {
+			summary = fmt.Sprintf("[群%s] %s 戳了戳 %s", groupID, userID, targetID)
+		} else {
+			summary = fmt.Sprintf("[私聊] %s 戳了戳 %s", userID, targetID)
+		}
+	case "group_ban":
+		duration, _ := msg["duration"].(float64)
+		detail["duration"] = int64(duration)
+		target := "用户 " + userID
+		if userID == "0" {
+			target = "全体成员"
+		}
+		if subType == "lift_ban" {
+			summary = fmt.Sprintf("[群%s] %s 解除了%s的禁言", groupID, operatorID, target)
+		} else if userID == "0" {
+			summary = fmt.Sprintf("[群%s] %s 开启了全体禁言", groupID, operatorID)
+		} else {
+			summary = fmt.Sprintf("[群%s] %s 禁言%s %s", groupID, operatorID, target, formatDuration(int64(duration)))
+		}
+	case "group_admin":
+		if subType == "set" {
+			summary = fmt.Sprintf("[群%s] %s 被设为管理员", groupID, userID)
+		} else {
+			summary = fmt.Sprintf("[群%s] %s 被取消管理员", groupID, userID)
+		}
+	case "group_upload":
+		name, size := "", int64(0)
+		if f, ok := msg["file"].(map[string]interface{}); ok {
+			name, _ = f["name"].(string)
+			s, _ := f["size"].(float64)
+			size = int64(s)
+			detail["file"] = f
+		}
+		summary = fmt.Sprintf("[群%s] %s 上传了文件 %s (%s)", groupID, userID, name, formatSize(size))
+	case "essence":
+		messageID := idString(msg["message_id"])
+		senderID := idString(msg["sender_id"])
+		detail["messageId"] = messageID
+		detail["senderId"] = senderID
+		text := ""
+		if m, err := model.GetMessage(l.db, selfID, messageID); err == nil {
+			text = m.Text
+			detail["originalText"] = m.Text
+		}
+		action := "设为精华"
+		if subType == "delete" {
+			action = "移出精华"
+		}
+		summary = fmt.Sprintf("[群%s] %s 将 %s 的消息%s", groupID, operatorID, senderID, action)
+		if text != "" {
+			summary += ": " + truncate(text, 80)
+		}
 	default:
 		summary = fmt.Sprintf("通知: %s", noticeType)
 	}
 
+	detailJSON, _ := json.Marshal(detail)
 	return &model.Event{
 		Time:    time.Now().UnixMilli(),
 		Source:  "qq",
-		Type:    "notice." + noticeType,
+		Type:    "notice." + eventType,
 		Summary: summary,
+		Detail:  string(detailJSON),
 	}
 }
 
+// formatDuration 将禁言秒数格式化为中文时长
+func formatDuration(sec int64) string {
+	switch {
+	case sec >= 86400:
+		return fmt.Sprintf("%d天%d小时", sec/86400, sec%86400/3600)
+	case sec >= 3600:
+		return fmt.Sprintf("%d小时%d分钟", sec/3600, sec%3600/60)
+	case sec >= 60:
+		return fmt.Sprintf("%d分钟", sec/60)
+	}
+	return fmt.Sprintf("%d秒", sec)
+}
+
+// formatSize 格式化文件大小
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 func (l *Listener) parseRequestEvent(msg map[string]interface{}) *model.Event {
 	reqType, _ := msg["request_type"].(string)
 	comment, _ := msg["comment"].(string)
-	userID := fmt.Sprintf("%v", msg["user_id"])
+	userID := idString(msg["user_id"])
 
 	summary := ""
 	switch reqType {
@@ -325,7 +633,7 @@ func (l *Listener) parseRequestEvent(msg map[string]interface{}) *model.Event {
 			summary += fmt.Sprintf(" (%s)", comment)
 		}
 	case "group":
-		groupID := fmt.Sprintf("%v", msg["group_id"])
+		groupID := idString(msg["group_id"])
 		summary = fmt.Sprintf("入群请求: %s → 群%s", userID, groupID)
 		if comment != "" {
 			summary += fmt.Sprintf(" (%s)", comment)
@@ -334,6 +642,28 @@ func (l *Listener) parseRequestEvent(msg map[string]interface{}) *model.Event {
 		summary = fmt.Sprintf("请求: %s from %s", reqType, userID)
 	}
 
+	// 保存到请求收件箱，供面板审批
+	if flag, _ := msg["flag"].(string); flag != "" {
+		subType, _ := msg["sub_type"].(string)
+		req := &model.Request{
+			Flag:     flag,
+			Type:     reqType,
+			SubType:  subType,
+			SelfID:   idString(msg["self_id"]),
+			Instance: l.instance,
+			UserID:   userID,
+			Comment:  comment,
+		}
+		if reqType == "group" {
+			req.GroupID = idString(msg["group_id"])
+		}
+		if err := model.SaveRequest(l.db, req); err != nil {
+			log.Printf("[EventLog] 保存请求失败: %v", err)
+		} else if l.api != nil {
+			go l.applyRequestPolicy(req)
+		}
+	}
+
 	return &model.Event{
 		Time:    time.Now().UnixMilli(),
 		Source:  "qq",
@@ -343,6 +673,60 @@ func (l *Listener) parseRequestEvent(msg map[string]interface{}) *model.Event {
 	}
 }
 
+// saveMessage 将消息事件写入 messages 表，保留 message_id 与原始消息段
+func (l *Listener) saveMessage(msg map[string]interface{}, msgType, selfID, userID, card, nickname, text string) {
+	messageID := idString(msg["message_id"])
+	if messageID == "" {
+		return
+	}
+	m := &model.Message{
+		MessageID:   messageID,
+		SelfID:      selfID,
+		MessageType: msgType,
+		UserID:      userID,
+		SenderCard:  card,
+		SenderNick:  nickname,
+		Text:        text,
+	}
+	if t, ok := msg["time"].(float64); ok && t > 0 {
+		m.Time = int64(t) * 1000
+	}
+	if msgType == "group" {
+		m.GroupID = idString(msg["group_id"])
+		m.PeerID = m.GroupID
+	} else {
+		m.PeerID = userID
+		// 自己发出的私聊，会话对象是 target_id
+		if userID == selfID {
+			if target := idString(msg["target_id"]); target != "" {
+				m.PeerID = target
+			}
+		}
+	}
+	switch segs := msg["message"].(type) {
+	case []interface{}:
+		m.Segments, _ = json.Marshal(segs)
+	case string:
+		m.Segments, _ = json.Marshal([]map[string]interface{}{{"type": "text", "data": map[string]string{"text": segs}}})
+	}
+	if err := model.SaveMessage(l.db, m); err != nil {
+		log.Printf("[EventLog] 保存消息失败: %v", err)
+	}
+}
+
+// idString 将 JSON 数字 / 字符串形式的 ID 转为字符串，避免 %v 对大数输出科学计数法
+func idString(v interface{}) string {
+	switch id := v.(type) {
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	case string:
+		return id
+	case json.Number:
+		return id.String()
+	}
+	return ""
+}
+
 func truncate(s string, maxLen int) string {
 	runes := []rune(s)
 	if len(runes) <= maxLen {
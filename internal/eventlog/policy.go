@@ -0,0 +1,236 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+	"github.com/zhaoxinyi02/ClawPanel/internal/onebot"
+)
+
+// RequestRule 好友/入群请求自动处理规则，所有已填写的条件同时满足才算命中
+type RequestRule struct {
+	Name        string   `json:"name"`
+	Disabled    bool     `json:"disabled"`
+	RequestType string   `json:"requestType"` // friend / group，留空匹配全部
+	SubType     string   `json:"subType"`     // add / invite，仅入群请求
+	Keywords    []string `json:"keywords"`    // 验证消息包含任一关键词
+	Regex       string   `json:"regex"`       // 验证消息匹配正则
+	GroupIn     []string `json:"groupIn"`     // 群号在列表中
+	GroupNotIn  []string `json:"groupNotIn"`  // 群号不在列表中（白名单外）
+	Action      string   `json:"action"`      // approve / reject
+	Remark      string   `json:"remark"`      // 同意好友时的备注
+	Reason      string   `json:"reason"`      // 拒绝入群时的理由
+}
+
+// RequestPolicy admin-config.json 中的 requestPolicy 配置段
+type RequestPolicy struct {
+	Enabled             bool          `json:"enabled"`
+	MaxApprovalsPerHour int           `json:"maxApprovalsPerHour"` // 0 表示不限制
+	Rules               []RequestRule `json:"rules"`
+}
+
+// LoadRequestPolicy 从 admin-config.json 读取请求自动处理策略
+func LoadRequestPolicy(dataDir string) RequestPolicy {
+	var adminCfg struct {
+		RequestPolicy RequestPolicy `json:"requestPolicy"`
+	}
+	data, err := os.ReadFile(filepath.Join(dataDir, "admin-config.json"))
+	if err != nil {
+		return RequestPolicy{}
+	}
+	if err := json.Unmarshal(data, &adminCfg); err != nil {
+		log.Printf("[EventLog] requestPolicy 解析失败: %v", err)
+		return RequestPolicy{}
+	}
+	return adminCfg.RequestPolicy
+}
+
+// Match 返回第一条命中的规则，未命中返回 nil
+func (p RequestPolicy) Match(req *model.Request) *RequestRule {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Disabled || (rule.Action != "approve" && rule.Action != "reject") {
+			continue
+		}
+		if rule.matches(req) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *RequestRule) matches(req *model.Request) bool {
+	if r.RequestType != "" && r.RequestType != req.Type {
+		return false
+	}
+	if r.SubType != "" && r.SubType != req.SubType {
+		return false
+	}
+	if len(r.Keywords) > 0 {
+		hit := false
+		for _, kw := range r.Keywords {
+			if kw != "" && strings.Contains(strings.ToLower(req.Comment), strings.ToLower(kw)) {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			return false
+		}
+	}
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			log.Printf("[EventLog] 规则 %s 正则无效: %v", r.Name, err)
+			return false
+		}
+		if !re.MatchString(req.Comment) {
+			return false
+		}
+	}
+	if len(r.GroupIn) > 0 && !containsString(r.GroupIn, req.GroupID) {
+		return false
+	}
+	if len(r.GroupNotIn) > 0 && (req.GroupID == "" || containsString(r.GroupNotIn, req.GroupID)) {
+		return false
+	}
+	return true
+}
+
+// applyRequestPolicy 按策略自动处理请求，每个决定都会记录命中的规则名
+func (l *Listener) applyRequestPolicy(req *model.Request) {
+	policy := LoadRequestPolicy(l.policyDir)
+	if !policy.Enabled {
+		return
+	}
+	// 重复推送的请求可能已被处理
+	if stored, err := model.GetRequest(l.db, req.Flag); err != nil || stored.Status != model.RequestPending {
+		return
+	}
+	rule := policy.Match(req)
+	if rule == nil {
+		return
+	}
+	approve := rule.Action == "approve"
+	detail, _ := json.Marshal(map[string]interface{}{
+		"rule":    rule.Name,
+		"flag":    req.Flag,
+		"userId":  req.UserID,
+		"groupId": req.GroupID,
+		"comment": req.Comment,
+	})
+	what := DescribeRequest(req)
+
+	// 先预占一次自动同意名额，调用失败时归还，避免并发请求超出上限
+	var slot time.Time
+	if approve {
+		var ok bool
+		if slot, ok = approvals.reserve(policy.MaxApprovalsPerHour); !ok {
+			l.sysLog.LogDetail("qq", "request."+req.Type+".rate_limited",
+				l.tag()+fmt.Sprintf("自动同意已达每小时上限 (%d)，%s 保留待人工处理 (规则: %s)", policy.MaxApprovalsPerHour, what, rule.Name), string(detail))
+			return
+		}
+	}
+
+	var err error
+	note := rule.Reason
+	if approve {
+		note = rule.Remark
+	}
+	if req.Type == "group" {
+		err = l.api.SetGroupAddRequest(req.Flag, req.SubType, approve, note)
+	} else {
+		err = l.api.SetFriendAddRequest(req.Flag, approve, note)
+	}
+	if err != nil {
+		if approve {
+			approvals.release(slot)
+		}
+		l.sysLog.LogDetail("qq", "request."+req.Type+".failed",
+			l.tag()+fmt.Sprintf("自动处理失败: %s (规则: %s): %v", what, rule.Name, err), string(detail))
+		return
+	}
+
+	status, action := model.RequestRejected, "自动拒绝"
+	if approve {
+		status, action = model.RequestApproved, "自动同意"
+	}
+	model.SetRequestStatus(l.db, req.Flag, status, fmt.Sprintf("规则 %s", rule.Name))
+	l.sysLog.LogDetail("qq", "request."+req.Type+".auto_"+status,
+		l.tag()+fmt.Sprintf("%s%s (规则: %s)", action, what, rule.Name), string(detail))
+}
+
+// approvalLimiter 每小时自动同意次数的滑动窗口。maxApprovalsPerHour 是全局配置，
+// 所有 NapCat 实例共用同一个窗口
+type approvalLimiter struct {
+	mu    sync.Mutex
+	times []time.Time
+}
+
+var approvals = &approvalLimiter{}
+
+// reserve 检查窗口内的自动同意次数，允许时预占一次并返回占位时间
+func (a *approvalLimiter) reserve(maxPerHour int) (time.Time, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	cutoff := now.Add(-time.Hour)
+	kept := a.times[:0]
+	for _, t := range a.times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	a.times = kept
+	if maxPerHour > 0 && len(a.times) >= maxPerHour {
+		return time.Time{}, false
+	}
+	a.times = append(a.times, now)
+	return now, true
+}
+
+// release 归还 reserve 预占的一次（同意请求失败时）
+func (a *approvalLimiter) release(slot time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, t := range a.times {
+		if t.Equal(slot) {
+			a.times = append(a.times[:i], a.times[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetRequestPolicy 启用请求自动处理，策略每次从 dataDir/admin-config.json 读取
+func (l *Listener) SetRequestPolicy(dataDir string, api *onebot.Client) {
+	l.policyDir = dataDir
+	l.api = api
+}
+
+// DescribeRequest 生成请求的中文描述，用于活动日志
+func DescribeRequest(req *model.Request) string {
+	if req.Type == "group" {
+		if req.SubType == "invite" {
+			return fmt.Sprintf("入群邀请: %s 邀请加入群%s", req.UserID, req.GroupID)
+		}
+		return fmt.Sprintf("入群请求: %s → 群%s", req.UserID, req.GroupID)
+	}
+	return fmt.Sprintf("好友请求: %s", req.UserID)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if strings.TrimSpace(v) == s {
+			return true
+		}
+	}
+	return false
+}
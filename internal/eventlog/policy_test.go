@@ -0,0 +1,78 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+	"github.com/zhaoxinyi02/ClawPanel/internal/websocket"
+)
+
+// OneBot11 request 事件原样解码（数字为 float64）后经 parseRequestEvent 保存，规则应按纯数字群号 / QQ 号命中
+func TestRequestRuleMatchesNumericIDs(t *testing.T) {
+	db, err := model.InitDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	l := NewListener(db, NewPublisher(db, websocket.NewHub(), nil), "")
+	l.SetInstance("napcat-2", "")
+
+	raw := `{"post_type":"request","request_type":"group","sub_type":"invite","flag":"f1",
+		"self_id":1234567890,"user_id":3987654321,"group_id":987654321,"comment":"hello","time":1700000000}`
+	var msg map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatal(err)
+	}
+	event := l.parseRequestEvent(msg)
+	if want := "入群请求: 3987654321 → 群987654321 (hello)"; event.Summary != want {
+		t.Errorf("summary = %q, want %q", event.Summary, want)
+	}
+
+	req, err := model.GetRequest(db, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.SelfID != "1234567890" || req.UserID != "3987654321" || req.GroupID != "987654321" {
+		t.Fatalf("stored ids = %q / %q / %q", req.SelfID, req.UserID, req.GroupID)
+	}
+	if req.Instance != "napcat-2" {
+		t.Errorf("stored instance = %q, want napcat-2", req.Instance)
+	}
+
+	tests := []struct {
+		name string
+		rule RequestRule
+		want bool
+	}{
+		{"groupIn hit", RequestRule{GroupIn: []string{"987654321"}}, true},
+		{"groupIn miss", RequestRule{GroupIn: []string{"111"}}, false},
+		{"groupNotIn listed", RequestRule{GroupNotIn: []string{"987654321"}}, false},
+		{"groupNotIn other", RequestRule{GroupNotIn: []string{"111"}}, true},
+		{"type and subtype", RequestRule{RequestType: "group", SubType: "invite", GroupIn: []string{"987654321"}}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.matches(req); got != tt.want {
+			t.Errorf("%s: matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApprovalLimiterRelease(t *testing.T) {
+	a := &approvalLimiter{}
+	first, ok := a.reserve(2)
+	if !ok {
+		t.Fatal("first reserve rejected")
+	}
+	if _, ok := a.reserve(2); !ok {
+		t.Fatal("second reserve rejected")
+	}
+	if _, ok := a.reserve(2); ok {
+		t.Fatal("third reserve allowed over limit")
+	}
+	// 同意失败归还名额后可再次预占
+	a.release(first)
+	if _, ok := a.reserve(2); !ok {
+		t.Fatal("reserve after release rejected")
+	}
+}
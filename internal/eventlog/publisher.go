@@ -0,0 +1,77 @@
+package eventlog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+	"github.com/zhaoxinyi02/ClawPanel/internal/webhook"
+	"github.com/zhaoxinyi02/ClawPanel/internal/websocket"
+)
+
+// Publisher 活动日志的统一发布入口：写入 SQLite、以 log-entry 推送给 WebSocket 客户端，
+// 并交给外发 Webhook。系统日志、OneBot11 监听器和外部推送都经由它发布
+type Publisher struct {
+	db       *sql.DB
+	hub      *websocket.Hub
+	webhooks *webhook.Dispatcher // 可为 nil
+}
+
+// NewPublisher 创建事件发布器，webhooks 为 nil 时不投递
+func NewPublisher(db *sql.DB, hub *websocket.Hub, webhooks *webhook.Dispatcher) *Publisher {
+	return &Publisher{db: db, hub: hub, webhooks: webhooks}
+}
+
+// Publish 保存事件并推送，成功后回填 ID
+func (p *Publisher) Publish(e *model.Event) error {
+	if e.Time == 0 {
+		e.Time = time.Now().UnixMilli()
+	}
+	id, err := model.AddEvent(p.db, e)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	p.notify(e)
+	return nil
+}
+
+// PublishAll 在一个事务中保存多条事件，全部写入后按顺序推送
+func (p *Publisher) PublishAll(events []*model.Event) error {
+	if err := model.AddEvents(p.db, events); err != nil {
+		return err
+	}
+	for _, e := range events {
+		p.notify(e)
+	}
+	return nil
+}
+
+// Broadcast 向订阅了 events:<source> 的 WebSocket 客户端推送 {"type": msgType, "data": data}
+func (p *Publisher) Broadcast(source, msgType string, data interface{}) []byte {
+	msg, _ := json.Marshal(map[string]interface{}{
+		"type": msgType,
+		"data": data,
+	})
+	p.hub.Publish(websocket.TopicEventPrefix+source, msg)
+	return msg
+}
+
+// DispatchTo 将已发布的事件额外投递到临时 Webhook 地址，未配置 Webhook 分发器时忽略
+func (p *Publisher) DispatchTo(url, secret string, e *model.Event) {
+	p.webhooks.DispatchTo(url, secret, e)
+}
+
+func (p *Publisher) notify(e *model.Event) {
+	p.Broadcast(e.Source, "log-entry", map[string]interface{}{
+		"id":       e.ID,
+		"time":     e.Time,
+		"source":   e.Source,
+		"type":     e.Type,
+		"summary":  e.Summary,
+		"detail":   e.Detail,
+		"severity": e.Severity,
+	})
+	p.webhooks.Dispatch(e)
+}
@@ -0,0 +1,115 @@
+package eventlog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// pruneStartDelay 启动后首次清理前的等待时间
+const pruneStartDelay = time.Minute
+
+// Pruner 按 settings 中的保留策略定期清理活动日志
+type Pruner struct {
+	db     *sql.DB
+	sysLog *SystemLogger
+
+	runMu  sync.Mutex // 同一时间只执行一次清理
+	stopCh chan struct{}
+}
+
+// NewPruner 创建活动日志清理器
+func NewPruner(db *sql.DB, sysLog *SystemLogger) *Pruner {
+	return &Pruner{db: db, sysLog: sysLog, stopCh: make(chan struct{})}
+}
+
+// Start 启动后台清理，每轮重新读取策略，修改周期或启用状态后下一轮生效
+func (p *Pruner) Start() {
+	go func() {
+		wait := pruneStartDelay
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-time.After(wait):
+			}
+			policy := model.GetRetentionPolicy(p.db)
+			if policy.Enabled {
+				p.run(policy, false)
+			}
+			wait = time.Duration(policy.IntervalMinutes) * time.Minute
+		}
+	}()
+}
+
+// Stop 停止后台清理
+func (p *Pruner) Stop() {
+	close(p.stopCh)
+}
+
+// Run 按当前策略立即清理一次，vacuum 为 true 时强制 VACUUM
+func (p *Pruner) Run(vacuum bool) model.PruneResult {
+	return p.run(model.GetRetentionPolicy(p.db), vacuum)
+}
+
+func (p *Pruner) run(policy model.RetentionPolicy, vacuum bool) model.PruneResult {
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+
+	result := model.PruneEvents(p.db, policy, vacuum)
+	if result.Error != "" {
+		log.Printf("[EventLog] 清理活动日志失败: %s", result.Error)
+		p.sysLog.LogDetail("system", "events.prune.failed", "活动日志自动清理失败: "+result.Error, "")
+		return result
+	}
+	if result.Deleted > 0 || result.Deliveries > 0 || result.Messages > 0 || result.UserStats > 0 || result.Vacuumed {
+		summary := fmt.Sprintf("活动日志清理完成：删除 %d 条%s", result.Deleted, describeDeleted(result.BySource))
+		if result.Messages > 0 {
+			summary += fmt.Sprintf("，消息记录 %d 条", result.Messages)
+		}
+		if result.UserStats > 0 {
+			summary += fmt.Sprintf("，用户发言统计 %d 条", result.UserStats)
+		}
+		if result.Deliveries > 0 {
+			summary += fmt.Sprintf("，Webhook 投递记录 %d 条", result.Deliveries)
+		}
+		summary += fmt.Sprintf("，用时 %dms", result.DurationMs)
+		if result.Vacuumed {
+			summary += "，已 VACUUM"
+		}
+		log.Printf("[EventLog] %s", summary)
+		detail, _ := json.Marshal(result)
+		p.sysLog.LogDetail("system", "events.pruned", summary, string(detail))
+	}
+	return result
+}
+
+// describeDeleted 按来源列出删除数量，如 " (system 120, 其他 3000)"
+func describeDeleted(bySource map[string]int64) string {
+	sources := make([]string, 0, len(bySource))
+	for source, n := range bySource {
+		if n > 0 {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return ""
+	}
+	sort.Strings(sources)
+	parts := make([]string, len(sources))
+	for i, source := range sources {
+		name := source
+		if source == "*" {
+			name = "其他"
+		}
+		parts[i] = fmt.Sprintf("%s %d", name, bySource[source])
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
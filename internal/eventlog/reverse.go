@@ -0,0 +1,217 @@
+package eventlog
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+)
+
+var reverseUpgrader = gorilla.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 1024,
+	// OneBot 实现不是浏览器，不校验 Origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ReverseConn 一个反向 WebSocket 连接的信息
+type ReverseConn struct {
+	SelfID      string `json:"selfId"`
+	Role        string `json:"role"` // X-Client-Role: Universal / Event / API
+	RemoteAddr  string `json:"remoteAddr"`
+	ConnectedAt int64  `json:"connectedAt"`
+	LastEventAt int64  `json:"lastEventAt"`
+
+	conn    *gorilla.Conn
+	writeMu sync.Mutex
+}
+
+// OneBot11 反向 WebSocket 客户端角色
+const (
+	RoleUniversal = "Universal"
+	RoleEvent     = "Event"
+	RoleAPI       = "API"
+)
+
+// clientRole 规范化 X-Client-Role，缺省为 Universal
+func clientRole(v string) string {
+	switch {
+	case strings.EqualFold(v, RoleEvent):
+		return RoleEvent
+	case strings.EqualFold(v, RoleAPI):
+		return RoleAPI
+	default:
+		return RoleUniversal
+	}
+}
+
+// ReverseServer OneBot11 反向 WebSocket 服务端：NapCat 主动连接面板推送事件，
+// 按 X-Self-ID 区分多个机器人，事件交给对应账号的 Listener 处理。
+// 同一账号可以分别建立 Event 与 API 连接，连接按 QQ 号 + 角色区分
+type ReverseServer struct {
+	token  func() string
+	pick   func(selfID string) *Listener
+	sysLog *SystemLogger
+
+	mu    sync.Mutex
+	conns map[string]*ReverseConn // selfID/role → 连接
+}
+
+func reverseKey(selfID, role string) string { return selfID + "/" + role }
+
+// NewReverseServer 创建反向 WebSocket 服务端。token 每次握手时读取；
+// pick 按机器人 QQ 号选择处理事件的 Listener，不可返回 nil
+func NewReverseServer(token func() string, pick func(selfID string) *Listener, sysLog *SystemLogger) *ReverseServer {
+	return &ReverseServer{
+		token:  token,
+		pick:   pick,
+		sysLog: sysLog,
+		conns:  map[string]*ReverseConn{},
+	}
+}
+
+// ServeHTTP 校验 access_token 与 X-Self-ID 后升级为 WebSocket 并接收事件
+func (s *ReverseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := s.token()
+	if token == "" {
+		http.Error(w, "reverse websocket access_token not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(requestToken(r)), []byte(token)) != 1 {
+		log.Printf("[EventLog] 反向 WS 鉴权失败: %s", r.RemoteAddr)
+		http.Error(w, "invalid access_token", http.StatusUnauthorized)
+		return
+	}
+	selfID := strings.TrimSpace(r.Header.Get("X-Self-ID"))
+	if selfID == "" {
+		http.Error(w, "X-Self-ID required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := reverseUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("[EventLog] 反向 WS 升级失败: %v", err)
+		return
+	}
+	rc := &ReverseConn{
+		SelfID:      selfID,
+		Role:        clientRole(r.Header.Get("X-Client-Role")),
+		RemoteAddr:  r.RemoteAddr,
+		ConnectedAt: time.Now().UnixMilli(),
+		conn:        conn,
+	}
+	key := reverseKey(selfID, rc.Role)
+
+	// 同一账号同一角色重复连接时断开旧连接
+	s.mu.Lock()
+	if old := s.conns[key]; old != nil {
+		old.conn.Close()
+	}
+	s.conns[key] = rc
+	s.mu.Unlock()
+
+	// Event / Universal 连接推送事件，期间该账号的正向连接不再处理事件
+	l := s.pick(selfID)
+	events := rc.Role != RoleAPI
+	if events {
+		l.reverseConns.Add(1)
+	}
+	log.Printf("[EventLog] 反向 WS 已连接: self_id=%s role=%s from %s", selfID, rc.Role, r.RemoteAddr)
+	s.sysLog.Log("system", "napcat.reverse.connected", fmt.Sprintf("%sNapCat 反向 WebSocket 已连接 (QQ %s, %s)", l.tag(), selfID, rc.Role))
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		s.mu.Lock()
+		rc.LastEventAt = time.Now().UnixMilli()
+		s.mu.Unlock()
+		// API 连接只返回调用结果，不含事件
+		if events {
+			l.processMessage(msg, true)
+		}
+	}
+	conn.Close()
+	if events {
+		l.reverseConns.Add(-1)
+	}
+
+	s.mu.Lock()
+	replaced := s.conns[key] != rc
+	if !replaced {
+		delete(s.conns, key)
+	}
+	s.mu.Unlock()
+	if !replaced {
+		log.Printf("[EventLog] 反向 WS 断开: self_id=%s role=%s", selfID, rc.Role)
+		s.sysLog.Log("system", "napcat.reverse.disconnected", fmt.Sprintf("%sNapCat 反向 WebSocket 连接断开 (QQ %s, %s)", l.tag(), selfID, rc.Role))
+	}
+}
+
+// Send 通过反向连接向指定账号发送 OneBot11 API 调用，优先使用 API 连接，其次 Universal 连接
+func (s *ReverseServer) Send(selfID string, v interface{}) error {
+	s.mu.Lock()
+	rc := s.conns[reverseKey(selfID, RoleAPI)]
+	if rc == nil {
+		rc = s.conns[reverseKey(selfID, RoleUniversal)]
+	}
+	s.mu.Unlock()
+	if rc == nil {
+		return fmt.Errorf("QQ %s 没有可用于 API 调用的反向 WebSocket 连接", selfID)
+	}
+	rc.writeMu.Lock()
+	defer rc.writeMu.Unlock()
+	return rc.conn.WriteJSON(v)
+}
+
+// Connections 当前反向连接列表（按 QQ 号、角色排序）
+func (s *ReverseServer) Connections() []ReverseConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]ReverseConn, 0, len(s.conns))
+	for _, rc := range s.conns {
+		list = append(list, ReverseConn{
+			SelfID:      rc.SelfID,
+			Role:        rc.Role,
+			RemoteAddr:  rc.RemoteAddr,
+			ConnectedAt: rc.ConnectedAt,
+			LastEventAt: rc.LastEventAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].SelfID != list[j].SelfID {
+			return list[i].SelfID < list[j].SelfID
+		}
+		return list[i].Role < list[j].Role
+	})
+	return list
+}
+
+// Close 断开全部反向连接
+func (s *ReverseServer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rc := range s.conns {
+		rc.conn.Close()
+	}
+}
+
+// requestToken 从 Authorization: Bearer/Token 头或 access_token 参数读取令牌
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		for _, prefix := range []string{"Bearer ", "Token "} {
+			if strings.HasPrefix(auth, prefix) {
+				return strings.TrimSpace(strings.TrimPrefix(auth, prefix))
+			}
+		}
+		return strings.TrimSpace(auth)
+	}
+	return r.URL.Query().Get("access_token")
+}
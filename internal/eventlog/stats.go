@@ -0,0 +1,57 @@
+package eventlog
+
+import (
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// statsInterval 后台汇总消息统计的周期
+const statsInterval = time.Minute
+
+// StatsRoller 定期把新消息累加到统计表，查询前也可调用 Refresh 补齐
+type StatsRoller struct {
+	db *sql.DB
+
+	mu     sync.Mutex // 同一时间只执行一次汇总
+	stopCh chan struct{}
+}
+
+// NewStatsRoller 创建消息统计汇总器
+func NewStatsRoller(db *sql.DB) *StatsRoller {
+	return &StatsRoller{db: db, stopCh: make(chan struct{})}
+}
+
+// Start 立即汇总一次历史消息，之后每分钟汇总新增消息
+func (r *StatsRoller) Start() {
+	go func() {
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+		r.Refresh()
+		for {
+			select {
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				r.Refresh()
+			}
+		}
+	}()
+}
+
+// Stop 停止后台汇总
+func (r *StatsRoller) Stop() {
+	close(r.stopCh)
+}
+
+// Refresh 汇总上次之后新增的消息，出错时只记录日志，统计查询仍返回已汇总的数据
+func (r *StatsRoller) Refresh() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := model.RollupStats(r.db); err != nil {
+		log.Printf("[Stats] 汇总消息统计失败: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// GetAlertRules 获取消息告警规则列表
+func GetAlertRules(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := model.GetAlertRules(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "rules": rules})
+	}
+}
+
+// CreateAlertRule 新建告警规则
+func CreateAlertRule(db *sql.DB, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		saveAlertRule(c, db, 0, sysLog)
+	}
+}
+
+// UpdateAlertRule 修改告警规则
+func UpdateAlertRule(db *sql.DB, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid id"})
+			return
+		}
+		saveAlertRule(c, db, id, sysLog)
+	}
+}
+
+// DeleteAlertRule 删除告警规则
+func DeleteAlertRule(db *sql.DB, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid id"})
+			return
+		}
+		if err := model.DeleteAlertRule(db, id); err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "rule not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		eventlog.ReloadAlertRules(db)
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			sysLog[0].Log("system", "alert.rule.deleted", fmt.Sprintf("告警规则 #%d 已删除", id))
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// alertRuleRequest 新建 / 修改告警规则的请求体，webhookSecret 省略时保留原值，传空字符串则清除
+type alertRuleRequest struct {
+	model.AlertRule
+	WebhookSecret *string `json:"webhookSecret"`
+}
+
+func saveAlertRule(c *gin.Context, db *sql.DB, id int64, sysLog []*eventlog.SystemLogger) {
+	var req alertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	rule := req.AlertRule
+	rule.ID = id
+	if req.WebhookSecret != nil {
+		rule.WebhookSecret = *req.WebhookSecret
+	} else if id > 0 {
+		if existing, err := model.GetAlertRule(db, id); err == nil {
+			rule.WebhookSecret = existing.WebhookSecret
+		}
+	}
+	rule.HasWebhookSecret = rule.WebhookSecret != ""
+	if err := validateAlertRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	if err := model.SaveAlertRule(db, &rule); err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "rule not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	eventlog.ReloadAlertRules(db)
+	if len(sysLog) > 0 && sysLog[0] != nil {
+		action := "updated"
+		if id == 0 {
+			action = "created"
+		}
+		sysLog[0].Log("system", "alert.rule."+action, fmt.Sprintf("告警规则已保存: #%d %s", rule.ID, rule.Name))
+	}
+	if id == 0 {
+		rule, _ := model.GetAlertRule(db, rule.ID)
+		c.JSON(http.StatusOK, gin.H{"ok": true, "rule": rule})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "rule": rule})
+}
+
+// validateAlertRule 校验并整理规则字段，至少需要一个匹配条件
+func validateAlertRule(r *model.AlertRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return fmt.Errorf("name required")
+	}
+	if r.MessageType != "" && r.MessageType != "group" && r.MessageType != "private" {
+		return fmt.Errorf("messageType must be group, private or empty")
+	}
+	r.Keywords = trimList(r.Keywords)
+	r.UserIDs = trimList(r.UserIDs)
+	r.GroupIDs = trimList(r.GroupIDs)
+	if r.Regex != "" {
+		if _, err := regexp.Compile(r.Regex); err != nil {
+			return fmt.Errorf("invalid regex: %v", err)
+		}
+	}
+	if len(r.Keywords) == 0 && r.Regex == "" && len(r.UserIDs) == 0 && len(r.GroupIDs) == 0 {
+		return fmt.Errorf("at least one of keywords, regex, userIds or groupIds required")
+	}
+	r.WebhookURL = strings.TrimSpace(r.WebhookURL)
+	if r.WebhookURL != "" && !strings.HasPrefix(r.WebhookURL, "http://") && !strings.HasPrefix(r.WebhookURL, "https://") {
+		return fmt.Errorf("webhookUrl must be http(s)")
+	}
+	return nil
+}
+
+func trimList(list []string) []string {
+	out := []string{}
+	for _, v := range list {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -1,23 +1,24 @@
 package handler
 
 import (
-	"bytes"
 	"crypto/md5"
-	"crypto/sha256"
-	"encoding/base64"
+	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	qrcode "github.com/skip2/go-qrcode"
+	"github.com/zhaoxinyi02/ClawPanel/internal/channel"
 	"github.com/zhaoxinyi02/ClawPanel/internal/config"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+	"github.com/zhaoxinyi02/ClawPanel/internal/onebot"
 )
 
 // === Admin Config ===
@@ -67,9 +68,8 @@ func loadAdminConfig(cfg *config.Config) map[string]interface{} {
 			"port":  cfg.Port,
 		},
 	}
-	data, err := os.ReadFile(adminConfigPath(cfg))
-	if err == nil {
-		json.Unmarshal(data, &result)
+	for k, v := range cfg.ReadAdminConfig() {
+		result[k] = v
 	}
 	return result
 }
@@ -123,145 +123,209 @@ func SetSudoPassword(cfg *config.Config) gin.HandlerFunc {
 
 // === Bot Operations (OneBot proxy) ===
 
-func onebotWsUrl(cfg *config.Config) string {
-	ocCfg := readOpenClawJSON(cfg)
-	if channels, ok := ocCfg["channels"].(map[string]interface{}); ok {
-		if qq, ok := channels["qq"].(map[string]interface{}); ok {
-			if wsUrl, ok := qq["wsUrl"].(string); ok && wsUrl != "" {
-				return wsUrl
-			}
-		}
-	}
-	return "ws://127.0.0.1:3001"
-}
-
+// GetBotGroups 获取群列表（服务端分页 + 搜索，?refresh=true 强制刷新缓存）
 func GetBotGroups(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(200, gin.H{"ok": true, "groups": []interface{}{}})
+		if qq, ok := qqChannel(c); ok {
+			writeContactsPage(c, qq, "group", "groups")
+		}
 	}
 }
 
+// GetBotFriends 获取好友列表（服务端分页 + 搜索，?refresh=true 强制刷新缓存）
 func GetBotFriends(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(200, gin.H{"ok": true, "friends": []interface{}{}})
+		if qq, ok := qqChannel(c); ok {
+			writeContactsPage(c, qq, "friend", "friends")
+		}
 	}
 }
 
-func BotSend(cfg *config.Config) gin.HandlerFunc {
+// BotSend 通过 OneBot11 发送私聊/群消息，message 可为纯文本或消息段数组
+func BotSend(cfg *config.Config, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(200, gin.H{"ok": true})
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
+		var body struct {
+			Type    string          `json:"type"`
+			ID      json.Number     `json:"id"`
+			Message json.RawMessage `json:"message"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		targetID, err := strconv.ParseInt(body.ID.String(), 10, 64)
+		if err != nil || targetID <= 0 {
+			c.JSON(400, gin.H{"ok": false, "error": "id required"})
+			return
+		}
+		if body.Type != "private" && body.Type != "group" {
+			c.JSON(400, gin.H{"ok": false, "error": "type must be private or group"})
+			return
+		}
+		segs, err := buildOnebotMessage(cfg, body.Message)
+		if err != nil {
+			c.JSON(400, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+
+		target := fmt.Sprintf("[私聊%d]", targetID)
+		if body.Type == "group" {
+			target = fmt.Sprintf("[群%d]", targetID)
+		}
+		preview := segmentsPreview(segs)
+		res, err := qq.Send(channel.Target{ID: body.ID.String(), Group: body.Type == "group"}, channel.Message{Segments: segs})
+		if err != nil {
+			if len(sysLog) > 0 && sysLog[0] != nil {
+				sysLog[0].LogDetail("system", "bot.send.failed", fmt.Sprintf("%s 消息发送失败: %s", target, err.Error()), preview)
+			}
+			c.JSON(502, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			sysLog[0].LogDetail("system", "bot."+body.Type+".send", fmt.Sprintf("%s ← 面板发送: %s", target, truncateStr(preview, 80)), preview)
+		}
+		messageID, _ := strconv.ParseInt(res.MessageID, 10, 64)
+		c.JSON(200, gin.H{"ok": true, "messageId": messageID})
 	}
 }
 
-func BotReconnect(cfg *config.Config) gin.HandlerFunc {
+func BotReconnect(cfg *config.Config, listeners eventlog.Listeners) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
+		qq.InvalidateContacts()
+		if l := listeners[qq.InstanceID()]; l != nil {
+			l.Reconnect()
+		}
 		c.JSON(200, gin.H{"ok": true})
 	}
 }
 
 // === Requests (approval) ===
 
-func GetRequests(cfg *config.Config) gin.HandlerFunc {
+// GetRequests 获取好友/入群请求列表，默认仅返回待处理请求（?status=all 返回全部）
+func GetRequests(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(200, gin.H{"ok": true, "requests": []interface{}{}})
+		status := c.DefaultQuery("status", model.RequestPending)
+		if status == "all" {
+			status = ""
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "200"))
+		if limit <= 0 {
+			limit = 200
+		}
+		requests, err := model.GetRequests(db, status, limit)
+		if err != nil {
+			c.JSON(500, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"ok": true, "requests": requests})
 	}
 }
 
-func ApproveRequest(cfg *config.Config) gin.HandlerFunc {
+// ApproveRequest 同意请求，可选 remark（好友备注）
+func ApproveRequest(db *sql.DB, cfg *config.Config, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(200, gin.H{"ok": true})
+		var body struct {
+			Remark string `json:"remark"`
+		}
+		c.ShouldBindJSON(&body)
+		handleRequest(c, db, true, body.Remark, sysLog...)
 	}
 }
 
-func RejectRequest(cfg *config.Config) gin.HandlerFunc {
+// RejectRequest 拒绝请求，可选 reason（拒绝理由，仅入群请求有效）
+func RejectRequest(db *sql.DB, cfg *config.Config, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(200, gin.H{"ok": true})
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		c.ShouldBindJSON(&body)
+		handleRequest(c, db, false, body.Reason, sysLog...)
 	}
 }
 
-// === NapCat Login Proxy ===
-
-const NAPCAT_WEBUI = "http://127.0.0.1:6099"
-
-var napcatCredential string
-
-func napcatProxy(method, path string, body interface{}, credential string) (map[string]interface{}, error) {
-	var bodyReader io.Reader
-	if body != nil {
-		data, _ := json.Marshal(body)
-		bodyReader = bytes.NewReader(data)
+func handleRequest(c *gin.Context, db *sql.DB, approve bool, note string, sysLog ...*eventlog.SystemLogger) {
+	flag := c.Param("flag")
+	req, err := model.GetRequest(db, flag)
+	if err == sql.ErrNoRows {
+		c.JSON(404, gin.H{"ok": false, "error": "请求不存在"})
+		return
 	}
-	req, err := http.NewRequest(method, NAPCAT_WEBUI+path, bodyReader)
 	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if credential != "" {
-		req.Header.Set("Authorization", "Bearer "+credential)
+		c.JSON(500, gin.H{"ok": false, "error": err.Error()})
+		return
 	}
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	data, _ := io.ReadAll(resp.Body)
-	var result map[string]interface{}
-	if err := json.Unmarshal(data, &result); err != nil {
-		return map[string]interface{}{"raw": string(data)}, nil
+	if req.Status != model.RequestPending {
+		c.JSON(409, gin.H{"ok": false, "error": "请求已处理: " + req.Status})
+		return
 	}
-	return result, nil
-}
 
-func napcatAuth(cfg *config.Config) string {
-	if napcatCredential != "" {
-		return napcatCredential
-	}
-	adminCfg := loadAdminConfig(cfg)
-	webuiToken := "openclaw-qq-admin"
-	if napcat, ok := adminCfg["napcat"].(map[string]interface{}); ok {
-		if t, ok := napcat["webuiToken"].(string); ok && t != "" {
-			webuiToken = t
+	// 未指定实例时交给收到该请求的实例处理，旧记录按 QQ 号查找
+	var qq *channel.QQAdapter
+	if c.Query("instance") == "" {
+		if req.Instance != "" {
+			qq, _ = channel.QQInstance(req.Instance)
+		}
+		if qq == nil {
+			qq, _ = channel.QQInstanceBySelfID(req.SelfID)
+		}
+		if qq == nil && len(channel.QQInstances()) > 1 {
+			log.Printf("[Request] 请求 %s 无法确定所属实例 (instance=%q self_id=%q)，交给默认实例处理", req.Flag, req.Instance, req.SelfID)
 		}
 	}
-	if envToken := os.Getenv("WEBUI_TOKEN"); envToken != "" {
-		webuiToken = envToken
+	if qq == nil {
+		var ok bool
+		if qq, ok = qqChannel(c); !ok {
+			return
+		}
 	}
-	hash := sha256.Sum256([]byte(webuiToken + ".napcat"))
-	hashStr := fmt.Sprintf("%x", hash)
-	r, err := napcatProxy("POST", "/api/auth/login", map[string]string{"hash": hashStr}, "")
-	if err == nil {
-		if code, ok := r["code"].(float64); ok && code == 0 {
-			if data, ok := r["data"].(map[string]interface{}); ok {
-				if cred, ok := data["Credential"].(string); ok {
-					napcatCredential = cred
-				}
-			}
+	if err := setOnebotRequest(qq.OneBot(), req, approve, note); err != nil {
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			sysLog[0].LogDetail("qq", "request."+req.Type+".failed", fmt.Sprintf("处理请求失败 (%s): %s", eventlog.DescribeRequest(req), err.Error()), req.Flag)
 		}
+		c.JSON(502, gin.H{"ok": false, "error": err.Error()})
+		return
 	}
-	return napcatCredential
-}
 
-func napcatApiCall(cfg *config.Config, method, path string, body interface{}) (map[string]interface{}, error) {
-	cred := napcatAuth(cfg)
-	r, err := napcatProxy(method, path, body, cred)
-	if err != nil {
-		return nil, err
+	status, action := model.RequestRejected, "已拒绝"
+	if approve {
+		status, action = model.RequestApproved, "已同意"
 	}
-	// If unauthorized, retry
-	if code, ok := r["code"].(float64); ok && code == -1 {
-		if msg, ok := r["message"].(string); ok && strings.Contains(strings.ToLower(msg), "unauthorized") {
-			napcatCredential = ""
-			cred = napcatAuth(cfg)
-			return napcatProxy(method, path, body, cred)
+	model.SetRequestStatus(db, req.Flag, status, note)
+	if len(sysLog) > 0 && sysLog[0] != nil {
+		summary := fmt.Sprintf("%s%s", action, eventlog.DescribeRequest(req))
+		if note != "" {
+			summary += fmt.Sprintf(" (%s)", note)
 		}
+		sysLog[0].LogDetail("qq", "request."+req.Type+"."+status, summary, req.Flag)
 	}
-	return r, nil
+	c.JSON(200, gin.H{"ok": true, "status": status})
 }
 
+// setOnebotRequest 调用 set_friend_add_request / set_group_add_request
+func setOnebotRequest(api *onebot.Client, req *model.Request, approve bool, note string) error {
+	if req.Type == "group" {
+		return api.SetGroupAddRequest(req.Flag, req.SubType, approve, note)
+	}
+	return api.SetFriendAddRequest(req.Flag, approve, note)
+}
+
+// === NapCat Login Proxy ===
+
 func NapcatLoginStatus(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		r, err := napcatApiCall(cfg, "POST", "/api/QQLogin/CheckLoginStatus", nil)
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
+		r, err := qq.NapcatCall("POST", "/api/QQLogin/CheckLoginStatus", nil)
 		if err != nil {
 			c.JSON(200, gin.H{"ok": false, "error": err.Error()})
 			return
@@ -273,7 +337,11 @@ func NapcatLoginStatus(cfg *config.Config) gin.HandlerFunc {
 
 func NapcatGetQRCode(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		r, err := napcatApiCall(cfg, "POST", "/api/QQLogin/GetQQLoginQrcode", nil)
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
+		r, err := qq.NapcatCall("POST", "/api/QQLogin/GetQQLoginQrcode", nil)
 		if err != nil {
 			c.JSON(200, gin.H{"ok": false, "error": err.Error()})
 			return
@@ -281,10 +349,8 @@ func NapcatGetQRCode(cfg *config.Config) gin.HandlerFunc {
 		r["ok"] = true
 		// NapCat returns a URL in data.qrcode — convert to base64 QR image
 		if data, ok := r["data"].(map[string]interface{}); ok {
-			if qrURL, ok := data["qrcode"].(string); ok && qrURL != "" && !strings.HasPrefix(qrURL, "data:") {
-				if png, err := qrcode.Encode(qrURL, qrcode.Medium, 256); err == nil {
-					data["qrcode"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
-				}
+			if qrURL, ok := data["qrcode"].(string); ok {
+				data["qrcode"] = channel.QRCodeDataURL(qrURL)
 			}
 		}
 		c.JSON(200, r)
@@ -293,22 +359,26 @@ func NapcatGetQRCode(cfg *config.Config) gin.HandlerFunc {
 
 func NapcatRefreshQRCode(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
 		// Get the old QR URL first so we can detect if it actually changed
-		oldR, _ := napcatApiCall(cfg, "POST", "/api/QQLogin/GetQQLoginQrcode", nil)
+		oldR, _ := qq.NapcatCall("POST", "/api/QQLogin/GetQQLoginQrcode", nil)
 		oldURL := ""
 		if oldData, ok := oldR["data"].(map[string]interface{}); ok {
 			oldURL, _ = oldData["qrcode"].(string)
 		}
 
 		// Call RefreshQRcode to invalidate old QR
-		napcatApiCall(cfg, "POST", "/api/QQLogin/RefreshQRcode", nil)
+		qq.NapcatCall("POST", "/api/QQLogin/RefreshQRcode", nil)
 		time.Sleep(500 * time.Millisecond)
 
 		// Retry up to 5 times to get a genuinely new QR code
 		var r map[string]interface{}
 		var err error
 		for i := 0; i < 5; i++ {
-			r, err = napcatApiCall(cfg, "POST", "/api/QQLogin/GetQQLoginQrcode", nil)
+			r, err = qq.NapcatCall("POST", "/api/QQLogin/GetQQLoginQrcode", nil)
 			if err == nil {
 				if data, ok := r["data"].(map[string]interface{}); ok {
 					if newURL, ok := data["qrcode"].(string); ok && newURL != "" && newURL != oldURL {
@@ -326,10 +396,8 @@ func NapcatRefreshQRCode(cfg *config.Config) gin.HandlerFunc {
 		r["ok"] = true
 		// NapCat returns a URL in data.qrcode — convert to base64 QR image
 		if data, ok := r["data"].(map[string]interface{}); ok {
-			if qrURL, ok := data["qrcode"].(string); ok && qrURL != "" && !strings.HasPrefix(qrURL, "data:") {
-				if png, err := qrcode.Encode(qrURL, qrcode.Medium, 256); err == nil {
-					data["qrcode"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
-				}
+			if qrURL, ok := data["qrcode"].(string); ok {
+				data["qrcode"] = channel.QRCodeDataURL(qrURL)
 			}
 		}
 		c.JSON(200, r)
@@ -338,7 +406,11 @@ func NapcatRefreshQRCode(cfg *config.Config) gin.HandlerFunc {
 
 func NapcatQuickLoginList(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		r, err := napcatApiCall(cfg, "POST", "/api/QQLogin/GetQuickLoginQQ", nil)
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
+		r, err := qq.NapcatCall("POST", "/api/QQLogin/GetQuickLoginQQ", nil)
 		if err != nil {
 			c.JSON(200, gin.H{"ok": false, "error": err.Error()})
 			return
@@ -350,11 +422,15 @@ func NapcatQuickLoginList(cfg *config.Config) gin.HandlerFunc {
 
 func NapcatQuickLogin(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
 		var body struct {
 			Uin string `json:"uin"`
 		}
 		c.ShouldBindJSON(&body)
-		r, err := napcatApiCall(cfg, "POST", "/api/QQLogin/SetQuickLogin", map[string]string{"uin": body.Uin})
+		r, err := qq.NapcatCall("POST", "/api/QQLogin/SetQuickLogin", map[string]string{"uin": body.Uin})
 		if err != nil {
 			c.JSON(200, gin.H{"ok": false, "error": err.Error()})
 			return
@@ -366,6 +442,10 @@ func NapcatQuickLogin(cfg *config.Config) gin.HandlerFunc {
 
 func NapcatPasswordLogin(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
 		var body struct {
 			Uin      string `json:"uin"`
 			Password string `json:"password"`
@@ -374,7 +454,7 @@ func NapcatPasswordLogin(cfg *config.Config) gin.HandlerFunc {
 		pwd := body.Password
 		hash := md5.Sum([]byte(pwd))
 		passwordMd5 := fmt.Sprintf("%x", hash)
-		r, err := napcatApiCall(cfg, "POST", "/api/QQLogin/PasswordLogin", map[string]string{"uin": body.Uin, "passwordMd5": passwordMd5})
+		r, err := qq.NapcatCall("POST", "/api/QQLogin/PasswordLogin", map[string]string{"uin": body.Uin, "passwordMd5": passwordMd5})
 		if err != nil {
 			c.JSON(200, gin.H{"ok": false, "error": err.Error()})
 			return
@@ -386,7 +466,11 @@ func NapcatPasswordLogin(cfg *config.Config) gin.HandlerFunc {
 
 func NapcatLoginInfo(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		r, err := napcatApiCall(cfg, "POST", "/api/QQLogin/GetQQLoginInfo", nil)
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
+		r, err := qq.NapcatCall("POST", "/api/QQLogin/GetQQLoginInfo", nil)
 		if err != nil {
 			c.JSON(200, gin.H{"ok": false, "error": err.Error()})
 			return
@@ -398,19 +482,23 @@ func NapcatLoginInfo(cfg *config.Config) gin.HandlerFunc {
 
 func NapcatLogout(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
 		// Restart the NapCat Docker container to force logout
-		go func() {
-			exec.Command("docker", "restart", "openclaw-qq").Run()
-		}()
+		qq.Logout()
 		c.JSON(200, gin.H{"ok": true, "message": "QQ 正在退出登录，容器重启中..."})
 	}
 }
 
 func RestartNapcat(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		go func() {
-			exec.Command("docker", "restart", "openclaw-qq").Run()
-		}()
+		qq, ok := qqChannel(c)
+		if !ok {
+			return
+		}
+		qq.Restart()
 		c.JSON(200, gin.H{"ok": true, "message": "NapCat 容器正在重启..."})
 	}
 }
@@ -419,36 +507,102 @@ func RestartNapcat(cfg *config.Config) gin.HandlerFunc {
 
 func WechatStatus(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(200, gin.H{"ok": true, "connected": false, "loggedIn": false, "name": ""})
+		wc, ok := wechatChannel(c)
+		if !ok {
+			return
+		}
+		st, err := wc.Status()
+		if err != nil {
+			c.JSON(200, gin.H{"ok": true, "connected": false, "loggedIn": false, "name": "", "error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"ok": true, "connected": st.Connected, "loggedIn": st.LoggedIn, "name": st.Nickname})
 	}
 }
 
 func WechatLoginUrl(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		adminCfg := loadAdminConfig(cfg)
-		token := ""
-		if wc, ok := adminCfg["wechat"].(map[string]interface{}); ok {
-			if t, ok := wc["token"].(string); ok {
-				token = t
-			}
+		wc, ok := wechatChannel(c)
+		if !ok {
+			return
 		}
-		host := c.Request.Host
-		if idx := strings.Index(host, ":"); idx > 0 {
-			host = host[:idx]
+		info, err := wc.Login()
+		if err != nil {
+			c.JSON(channelErrorStatus(err), gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		// 面板通过内网地址访问微信服务，浏览器需要使用面板所在主机名
+		externalUrl := info.URL
+		if u, err := url.Parse(info.URL); err == nil {
+			host := c.Request.Host
+			if idx := strings.Index(host, ":"); idx > 0 {
+				host = host[:idx]
+			}
+			if port := u.Port(); port != "" {
+				u.Host = host + ":" + port
+			} else {
+				u.Host = host
+			}
+			externalUrl = u.String()
 		}
-		externalUrl := fmt.Sprintf("http://%s:3002/login?token=%s", host, token)
-		c.JSON(200, gin.H{"ok": true, "externalUrl": externalUrl, "internalUrl": ""})
+		c.JSON(200, gin.H{"ok": true, "externalUrl": externalUrl, "internalUrl": info.URL})
 	}
 }
 
+// WechatSend 发送文本消息到联系人或群聊
 func WechatSend(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		wc, ok := wechatChannel(c)
+		if !ok {
+			return
+		}
+		var body struct {
+			To      string `json:"to"`
+			Content string `json:"content"`
+			IsRoom  bool   `json:"isRoom"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.To == "" || body.Content == "" {
+			c.JSON(400, gin.H{"ok": false, "error": "to and content required"})
+			return
+		}
+		if _, err := wc.Send(channel.Target{ID: body.To, Group: body.IsRoom}, channel.Message{Text: body.Content}); err != nil {
+			c.JSON(channelErrorStatus(err), gin.H{"ok": false, "error": err.Error()})
+			return
+		}
 		c.JSON(200, gin.H{"ok": true})
 	}
 }
 
+// WechatSendFile 发送文件：path 为工作区文件（multipart 上传），fileUrl 为远程文件
 func WechatSendFile(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		wc, ok := wechatChannel(c)
+		if !ok {
+			return
+		}
+		var body struct {
+			To      string `json:"to"`
+			Path    string `json:"path"`
+			FileURL string `json:"fileUrl"`
+			IsRoom  bool   `json:"isRoom"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.To == "" || (body.Path == "" && body.FileURL == "") {
+			c.JSON(400, gin.H{"ok": false, "error": "to and path (or fileUrl) required"})
+			return
+		}
+		msg := channel.Message{FileURL: body.FileURL}
+		if body.Path != "" {
+			abs, err := resolveWorkspaceFile(cfg, body.Path)
+			if err != nil {
+				c.JSON(404, gin.H{"ok": false, "error": err.Error()})
+				return
+			}
+			msg = channel.Message{FilePath: abs}
+		}
+		if _, err := wc.Send(channel.Target{ID: body.To, Group: body.IsRoom}, msg); err != nil {
+			c.JSON(channelErrorStatus(err), gin.H{"ok": false, "error": err.Error()})
+			return
+		}
 		c.JSON(200, gin.H{"ok": true})
 	}
 }
@@ -0,0 +1,514 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/channel"
+	"github.com/zhaoxinyi02/ClawPanel/internal/config"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/wechat"
+)
+
+// === Channel adapters ===
+
+// channelAdapter 按 ID 获取已注册的通道适配器，未注册时写入 404
+func channelAdapter(c *gin.Context, id string) (channel.Adapter, bool) {
+	a, ok := channel.Get(id)
+	if !ok {
+		c.JSON(404, gin.H{"ok": false, "error": "通道未注册: " + id})
+	}
+	return a, ok
+}
+
+// qqChannel 按 ?instance= 获取 QQ (NapCat) 实例适配器，未指定时为第一个实例，实例不存在时写入 404
+func qqChannel(c *gin.Context) (*channel.QQAdapter, bool) {
+	id := c.Query("instance")
+	qq, ok := channel.QQInstance(id)
+	if !ok {
+		c.JSON(404, gin.H{"ok": false, "error": "NapCat 实例不存在: " + id})
+	}
+	return qq, ok
+}
+
+// napcatInstanceStatus 并发查询各 NapCat 实例的登录状态、账号信息及群/好友数量
+func napcatInstanceStatus() []gin.H {
+	list, _ := queryNapcatInstances()
+	return list
+}
+
+// queryNapcatInstances 同 napcatInstanceStatus，另返回实例 ID → 通道状态，供调用方复用查询结果
+func queryNapcatInstances() ([]gin.H, map[string]*channel.Status) {
+	instances := channel.QQInstances()
+	list := make([]gin.H, len(instances))
+	statuses := make([]*channel.Status, len(instances))
+	var wg sync.WaitGroup
+	for i, qq := range instances {
+		wg.Add(1)
+		go func(i int, qq *channel.QQAdapter) {
+			defer wg.Done()
+			inst := qq.Instance()
+			info := gin.H{"id": inst.ID, "name": inst.Name, "container": inst.Container, "reachable": false, "connected": false}
+			st, err := qq.Status()
+			if err != nil {
+				st.Error = err.Error()
+				info["error"] = st.Error
+			}
+			statuses[i] = st
+			info["reachable"] = st.Connected
+			if st.LoggedIn {
+				info["connected"] = true
+				info["nickname"] = st.Nickname
+				if st.SelfID != "" {
+					info["selfId"] = st.SelfID
+				}
+				for k, v := range st.Extra {
+					info[k] = v
+				}
+			}
+			list[i] = info
+		}(i, qq)
+	}
+	wg.Wait()
+	byInstance := map[string]*channel.Status{}
+	for i, qq := range instances {
+		byInstance[qq.InstanceID()] = statuses[i]
+	}
+	return list, byInstance
+}
+
+// onebotConnections 按 NapCat 实例顺序返回 OneBot11 监听器的连接状态
+func onebotConnections(listeners eventlog.Listeners) []eventlog.ConnectionState {
+	list := []eventlog.ConnectionState{}
+	for _, qq := range channel.QQInstances() {
+		if l := listeners[qq.InstanceID()]; l != nil {
+			list = append(list, l.State())
+		}
+	}
+	return list
+}
+
+// GetBotConnection 获取 OneBot11 WebSocket 连接健康状态，?instance= 指定实例时只返回该实例
+func GetBotConnection(listeners eventlog.Listeners) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("instance") != "" {
+			qq, ok := qqChannel(c)
+			if !ok {
+				return
+			}
+			l := listeners[qq.InstanceID()]
+			if l == nil {
+				c.JSON(404, gin.H{"ok": false, "error": "该实例没有事件监听器"})
+				return
+			}
+			c.JSON(200, gin.H{"ok": true, "connection": l.State()})
+			return
+		}
+		c.JSON(200, gin.H{"ok": true, "connections": onebotConnections(listeners)})
+	}
+}
+
+// GetReverseConnections 获取 OneBot11 反向 WebSocket 配置与当前连接
+func GetReverseConnections(cfg *config.Config, server *eventlog.ReverseServer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rc := cfg.GetOneBotReverse()
+		conns := []eventlog.ReverseConn{}
+		if server != nil {
+			conns = server.Connections()
+		}
+		c.JSON(200, gin.H{
+			"ok":             true,
+			"enabled":        rc.Enabled,
+			"tokenSet":       rc.AccessToken != "",
+			"disableForward": rc.DisableForward,
+			"path":           "/onebot/v11/ws",
+			"connections":    conns,
+		})
+	}
+}
+
+// GetNapcatInstances 获取全部 NapCat 实例及其账号状态
+func GetNapcatInstances() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true, "instances": napcatInstanceStatus()})
+	}
+}
+
+// wechatChannel 获取微信通道适配器
+func wechatChannel(c *gin.Context) (*channel.WechatAdapter, bool) {
+	a, ok := channelAdapter(c, "wechat")
+	if !ok {
+		return nil, false
+	}
+	wc, ok := a.(*channel.WechatAdapter)
+	if !ok {
+		c.JSON(500, gin.H{"ok": false, "error": "wechat 通道类型错误"})
+	}
+	return wc, ok
+}
+
+// channelErrorStatus 将通道错误映射为 HTTP 状态码
+func channelErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, channel.ErrUnsupported):
+		return 501
+	case errors.Is(err, wechat.ErrUnauthorized):
+		return 401
+	case errors.Is(err, wechat.ErrNotLoggedIn):
+		return 409
+	case errors.Is(err, wechat.ErrTimeout):
+		return 504
+	case errors.Is(err, wechat.ErrUnavailable):
+		return 503
+	default:
+		return 502
+	}
+}
+
+// GetChannelStatus 获取指定通道状态
+func GetChannelStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a, ok := channelAdapter(c, c.Param("id"))
+		if !ok {
+			return
+		}
+		st, err := a.Status()
+		if err != nil {
+			st.Error = err.Error()
+		}
+		c.JSON(200, gin.H{"ok": true, "id": a.ID(), "label": channel.Label(a.ID()), "status": st})
+	}
+}
+
+// GetChannelContacts 获取指定通道联系人（?kind=friend|group，分页与搜索同 /bot/groups）
+func GetChannelContacts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a, ok := channelAdapter(c, c.Param("id"))
+		if !ok {
+			return
+		}
+		writeContactsPage(c, a, c.DefaultQuery("kind", "friend"), "contacts")
+	}
+}
+
+// writeContactsPage 拉取联系人并按 limit/offset/search/refresh 查询参数输出一页
+func writeContactsPage(c *gin.Context, a channel.Adapter, kind, key string) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	refresh := c.Query("refresh") == "true" || c.Query("refresh") == "1"
+
+	list, err := a.ListContacts(kind, refresh)
+	if err != nil {
+		c.JSON(channelErrorStatus(err), gin.H{"ok": false, "error": err.Error(), key: []interface{}{}})
+		return
+	}
+	items := filterContacts(list.Items, c.Query("search"))
+	start, end, limit := pageBounds(len(items), limit, offset)
+	c.JSON(200, gin.H{
+		"ok":       true,
+		key:        items[start:end],
+		"total":    len(items),
+		"limit":    limit,
+		"offset":   start,
+		"cachedAt": list.UpdatedAt.UnixMilli(),
+	})
+}
+
+// ChannelSend 通过指定通道发送消息
+func ChannelSend(cfg *config.Config, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a, ok := channelAdapter(c, c.Param("id"))
+		if !ok {
+			return
+		}
+		var body struct {
+			To       string          `json:"to"`
+			Group    bool            `json:"group"`
+			Text     string          `json:"text"`
+			Segments json.RawMessage `json:"segments"`
+			Path     string          `json:"path"`
+			FileURL  string          `json:"fileUrl"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.To == "" {
+			c.JSON(400, gin.H{"ok": false, "error": "to required"})
+			return
+		}
+		msg := channel.Message{Text: body.Text, FileURL: body.FileURL}
+		preview := body.Text
+		if len(body.Segments) > 0 {
+			segs, err := buildOnebotMessage(cfg, body.Segments)
+			if err != nil {
+				c.JSON(400, gin.H{"ok": false, "error": err.Error()})
+				return
+			}
+			msg.Segments = segs
+			preview = segmentsPreview(segs)
+		}
+		if body.Path != "" {
+			abs, err := resolveWorkspaceFile(cfg, body.Path)
+			if err != nil {
+				c.JSON(404, gin.H{"ok": false, "error": err.Error()})
+				return
+			}
+			msg.FilePath = abs
+			preview = "[文件] " + body.Path
+		} else if body.FileURL != "" && preview == "" {
+			preview = "[文件] " + body.FileURL
+		}
+
+		target := fmt.Sprintf("[%s] %s", channel.Label(a.ID()), body.To)
+		res, err := a.Send(channel.Target{ID: body.To, Group: body.Group}, msg)
+		if err != nil {
+			if len(sysLog) > 0 && sysLog[0] != nil {
+				sysLog[0].LogDetail("system", "channel.send.failed", fmt.Sprintf("%s 消息发送失败: %s", target, err.Error()), preview)
+			}
+			c.JSON(channelErrorStatus(err), gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			sysLog[0].LogDetail("system", "channel."+a.ID()+".send", fmt.Sprintf("%s ← 面板发送: %s", target, truncateStr(preview, 80)), preview)
+		}
+		c.JSON(200, gin.H{"ok": true, "messageId": res.MessageID})
+	}
+}
+
+// ChannelLogin 获取指定通道的登录引导（二维码或登录页）
+func ChannelLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a, ok := channelAdapter(c, c.Param("id"))
+		if !ok {
+			return
+		}
+		info, err := a.Login()
+		if err != nil {
+			c.JSON(channelErrorStatus(err), gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"ok": true, "login": info})
+	}
+}
+
+// ChannelLogout 退出指定通道登录
+func ChannelLogout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a, ok := channelAdapter(c, c.Param("id"))
+		if !ok {
+			return
+		}
+		if err := a.Logout(); err != nil {
+			c.JSON(channelErrorStatus(err), gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"ok": true})
+	}
+}
+
+// filterContacts 按 ID、名称或备注（不区分大小写）过滤
+func filterContacts(items []channel.Contact, search string) []channel.Contact {
+	search = strings.ToLower(strings.TrimSpace(search))
+	if search == "" {
+		return items
+	}
+	result := []channel.Contact{}
+	for _, ct := range items {
+		if strings.Contains(ct.ID, search) ||
+			strings.Contains(strings.ToLower(ct.Name), search) ||
+			strings.Contains(strings.ToLower(ct.Remark), search) {
+			result = append(result, ct)
+		}
+	}
+	return result
+}
+
+// pageBounds 将 limit/offset 规范化为切片边界，返回 start、end 和实际 limit
+func pageBounds(total, limit, offset int) (int, int, int) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end, limit
+}
+
+func truncateStr(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen]) + "..."
+}
+
+// === 消息段 ===
+
+// maxImageSize 通过工作区路径发送图片的大小上限
+const maxImageSize = 20 * 1024 * 1024
+
+// segmentID 将消息段中数字或字符串形式的 ID 转为字符串
+func segmentID(v interface{}) string {
+	switch id := v.(type) {
+	case json.Number:
+		return id.String()
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	case string:
+		return id
+	}
+	return fmt.Sprint(v)
+}
+
+// buildOnebotMessage 将请求中的 message（纯文本或消息段数组）规范化为 OneBot11 消息段。
+// 图片段可通过 data.path 引用工作区文件，发送时转为 base64:// 以兼容容器内的 NapCat。
+func buildOnebotMessage(cfg *config.Config, raw json.RawMessage) ([]channel.Segment, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("message required")
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("message required")
+		}
+		return []channel.Segment{{Type: "text", Data: map[string]interface{}{"text": text}}}, nil
+	}
+	// UseNumber 保留 QQ 号 / 消息 ID 的原始数字，避免 float64 转字符串时出现科学计数法
+	var segs []channel.Segment
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&segs); err != nil {
+		return nil, fmt.Errorf("message 必须是字符串或消息段数组")
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("message required")
+	}
+	for i := range segs {
+		seg := &segs[i]
+		if seg.Data == nil {
+			seg.Data = map[string]interface{}{}
+		}
+		switch seg.Type {
+		case "text":
+			if _, ok := seg.Data["text"].(string); !ok {
+				return nil, fmt.Errorf("第 %d 个消息段缺少 text", i+1)
+			}
+		case "at":
+			if seg.Data["qq"] == nil {
+				return nil, fmt.Errorf("第 %d 个消息段缺少 qq", i+1)
+			}
+			seg.Data["qq"] = segmentID(seg.Data["qq"])
+		case "reply", "face":
+			if seg.Data["id"] == nil {
+				return nil, fmt.Errorf("第 %d 个消息段缺少 id", i+1)
+			}
+			seg.Data["id"] = segmentID(seg.Data["id"])
+		case "image":
+			if p, _ := seg.Data["path"].(string); p != "" {
+				abs, err := resolveWorkspaceFile(cfg, p)
+				if err != nil {
+					return nil, err
+				}
+				info, _ := os.Stat(abs)
+				if info.Size() > maxImageSize {
+					return nil, fmt.Errorf("图片过大: %s", humanSize(info.Size()))
+				}
+				data, err := os.ReadFile(abs)
+				if err != nil {
+					return nil, err
+				}
+				delete(seg.Data, "path")
+				seg.Data["file"] = "base64://" + base64.StdEncoding.EncodeToString(data)
+			} else if f, _ := seg.Data["file"].(string); f == "" {
+				return nil, fmt.Errorf("第 %d 个消息段缺少 path 或 file", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("不支持的消息段类型: %s", seg.Type)
+		}
+	}
+	return segs, nil
+}
+
+// segmentsPreview 生成消息段的纯文本预览，用于活动日志
+func segmentsPreview(segs []channel.Segment) string {
+	var parts []string
+	for _, seg := range segs {
+		switch seg.Type {
+		case "text":
+			parts = append(parts, seg.Data["text"].(string))
+		case "at":
+			parts = append(parts, fmt.Sprintf("[At:%v]", seg.Data["qq"]))
+		case "image":
+			parts = append(parts, "[图片]")
+		case "face":
+			parts = append(parts, "[表情]")
+		case "reply":
+			parts = append(parts, fmt.Sprintf("[回复:%v]", seg.Data["id"]))
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// === Service endpoints ===
+
+// GetEndpoints 获取 NapCat / OneBot / 微信服务地址
+func GetEndpoints(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(200, gin.H{"ok": true, "endpoints": cfg.GetEndpoints()})
+	}
+}
+
+// SaveEndpoints 修改服务地址，立即生效：清空 NapCat 凭证与联系人缓存并重连 OneBot11 WebSocket
+func SaveEndpoints(cfg *config.Config, listeners eventlog.Listeners, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body config.Endpoints
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(400, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		for _, u := range []string{body.NapcatWebUI, body.OneBotHTTP, body.OneBotWS, body.WechatAPI} {
+			if u != "" && !strings.Contains(u, "://") {
+				c.JSON(400, gin.H{"ok": false, "error": "地址需包含协议: " + u})
+				return
+			}
+		}
+		for _, name := range []string{body.QQContainer, body.WechatContainer} {
+			if name == "" {
+				continue
+			}
+			if err := checkContainerName(name); err != nil {
+				c.JSON(400, gin.H{"ok": false, "error": err.Error()})
+				return
+			}
+		}
+		if err := cfg.SetEndpoints(body); err != nil {
+			c.JSON(500, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		endpoints := cfg.GetEndpoints()
+		// 未单独配置地址的实例会跟随变化
+		for _, qq := range channel.QQInstances() {
+			qq.Reload()
+			if l := listeners[qq.InstanceID()]; l != nil {
+				l.SetURL(qq.Instance().OneBotWS)
+			}
+		}
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			detail, _ := json.Marshal(endpoints)
+			sysLog[0].LogDetail("system", "system.endpoints.updated", "服务地址已更新", string(detail))
+		}
+		c.JSON(200, gin.H{"ok": true, "endpoints": endpoints})
+	}
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildOnebotMessageIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   string
+		field string
+		want  string
+	}{
+		{"at large qq", `[{"type":"at","data":{"qq":1234567890}}]`, "qq", "1234567890"},
+		{"at qq beyond int32", `[{"type":"at","data":{"qq":3987654321}}]`, "qq", "3987654321"},
+		{"at string qq", `[{"type":"at","data":{"qq":"all"}}]`, "qq", "all"},
+		{"reply negative id", `[{"type":"reply","data":{"id":-2147483648}}]`, "id", "-2147483648"},
+		{"reply large id", `[{"type":"reply","data":{"id":9007199254740993}}]`, "id", "9007199254740993"},
+		{"reply string id", `[{"type":"reply","data":{"id":"-123"}}]`, "id", "-123"},
+		{"face id", `[{"type":"face","data":{"id":178}}]`, "id", "178"},
+	}
+	for _, tt := range tests {
+		segs, err := buildOnebotMessage(nil, json.RawMessage(tt.raw))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := segs[0].Data[tt.field]; got != tt.want {
+			t.Errorf("%s: %s = %#v, want %q", tt.name, tt.field, got, tt.want)
+		}
+	}
+}
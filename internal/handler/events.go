@@ -2,35 +2,133 @@ package handler
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/ingest"
 	"github.com/zhaoxinyi02/ClawPanel/internal/model"
 )
 
-// GetEvents 获取事件日志
+// GetEvents 获取事件日志。source 可逗号分隔或重复传多个；type 为类型前缀；
+// since / until 为毫秒时间戳；search 为全文检索，默认按相关度排序，sort=time 时按时间。
+// 传 cursor 时按 (time, id) 向更早翻页，传 after 时按 id 拉取之后写入的事件，两者默认不统计总数
 func GetEvents(db *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
 		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-		source := c.Query("source")
-		search := c.Query("search")
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		q := eventFilter(c)
+		q.Limit = limit + 1 // 多取一条判断是否还有下一页
+		q.Offset = offset
 
-		events, total, err := model.GetEvents(db, limit, offset, source, search)
+		var err error
+		if v := c.Query("cursor"); v != "" {
+			if q.Before, err = model.ParseEventCursor(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+				return
+			}
+		}
+		if v := c.Query("after"); v != "" {
+			if q.After, err = model.ParseEventCursor(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+				return
+			}
+		}
+		if q.Before != nil && q.After != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "cursor and after are mutually exclusive"})
+			return
+		}
+		paging := q.Before != nil || q.After != nil
+		count := !paging
+		if v := c.Query("count"); v != "" {
+			count, _ = strconv.ParseBool(v)
+		}
+		q.SkipCount = !count
+
+		var events []model.Event
+		var total int
+		var head int64
+		if q.After != nil {
+			events, total, err = model.QueryEvents(db, q)
+		} else {
+			events, total, head, err = model.QueryEventsHead(db, q)
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
 			return
 		}
+		hasMore := len(events) > limit
+		if hasMore {
+			events = events[:limit]
+		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"ok":     true,
-			"events": events,
-			"total":  total,
-			"limit":  limit,
-			"offset": offset,
-		})
+		resp := gin.H{
+			"ok":      true,
+			"events":  events,
+			"limit":   limit,
+			"hasMore": hasMore,
+		}
+		if !paging {
+			resp["offset"] = offset
+		}
+		if total >= 0 {
+			resp["total"] = total
+		}
+		if q.After != nil {
+			// 按 id 正序：nextCursor 为本页最后写入的一条，无新事件时原样返回便于继续轮询
+			next := q.After
+			if len(events) > 0 {
+				next = model.CursorOf(events[len(events)-1])
+			}
+			resp["nextCursor"] = next.String()
+		} else {
+			// headCursor 指向查询时最新写入的事件，可作为 after 轮询之后写入的事件；
+			// nextCursor 继续向更早翻页，按相关度排序时不返回
+			resp["headCursor"] = (&model.EventCursor{ID: head}).String()
+			if hasMore && (q.Search == "" || q.SortByTime || paging) {
+				resp["nextCursor"] = model.CursorOf(events[len(events)-1]).String()
+			}
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// eventFilter 解析 /api/events 与导出共用的筛选参数
+func eventFilter(c *gin.Context) model.EventQuery {
+	q := model.EventQuery{
+		Sources:    splitQuery(c.QueryArray("source")),
+		TypePrefix: c.Query("type"),
+		Severity:   c.Query("severity"),
+		Search:     c.Query("search"),
+		SortByTime: c.Query("sort") == "time",
+	}
+	q.Since, _ = strconv.ParseInt(c.Query("since"), 10, 64)
+	q.Until, _ = strconv.ParseInt(c.Query("until"), 10, 64)
+	return q
+}
+
+// splitQuery 展开重复或逗号分隔的查询参数，去掉空值
+func splitQuery(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
 	}
+	return out
 }
 
 // ClearEvents 清空事件日志
@@ -44,38 +142,212 @@ func ClearEvents(db *sql.DB) gin.HandlerFunc {
 	}
 }
 
-// PostEvent 外部服务提交事件（无需认证）
-func PostEvent(db *sql.DB) gin.HandlerFunc {
+// GetEventRetention 获取活动日志保留策略
+func GetEventRetention(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "policy": model.GetRetentionPolicy(db)})
+	}
+}
+
+// SaveEventRetention 保存活动日志保留策略，下一轮自动清理时生效
+func SaveEventRetention(db *sql.DB, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req struct {
-			Source  string `json:"source"`
-			Type    string `json:"type"`
-			Summary string `json:"summary"`
-			Detail  string `json:"detail"`
+		var policy model.RetentionPolicy
+		if err := c.ShouldBindJSON(&policy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
 		}
-		if err := c.ShouldBindJSON(&req); err != nil || req.Summary == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "summary required"})
+		if policy.IntervalMinutes <= 0 {
+			policy.IntervalMinutes = 60
+		}
+		if policy.VacuumFreeRatio < 0 || policy.VacuumFreeRatio > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "vacuumFreeRatio must be between 0 and 1"})
 			return
 		}
+		if policy.UserStatsDays < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "userStatsDays must not be negative"})
+			return
+		}
+		limits := []model.RetentionLimit{policy.Default, policy.Messages}
+		for _, l := range policy.Sources {
+			limits = append(limits, l)
+		}
+		for _, l := range limits {
+			if l.MaxAgeDays < 0 || l.MaxRows < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "maxAgeDays and maxRows must not be negative"})
+				return
+			}
+		}
+		if err := model.SaveRetentionPolicy(db, policy); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			sysLog[0].Log("system", "events.retention.updated", fmt.Sprintf("活动日志保留策略已更新：默认 %d 天 / %d 条", policy.Default.MaxAgeDays, policy.Default.MaxRows))
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "policy": model.GetRetentionPolicy(db)})
+	}
+}
 
+// GetEventStorage 活动日志行数、表大小与最近一次清理结果
+func GetEventStorage(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		st, err := model.GetEventStorage(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "storage": st})
+	}
+}
+
+// PruneEvents 按保留策略立即清理，?vacuum=true 时强制 VACUUM
+func PruneEvents(pruner *eventlog.Pruner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		vacuum, _ := strconv.ParseBool(c.Query("vacuum"))
+		result := pruner.Run(vacuum)
+		if result.Error != "" {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": result.Error, "result": result})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "result": result})
+	}
+}
+
+// maxIngestBatch 批量推送单次最多事件数
+const maxIngestBatch = 500
+
+// ingestEvent 外部推送的单条事件，source / type 省略时为 openclaw / openclaw.action
+type ingestEvent struct {
+	Source   string `json:"source"`
+	Type     string `json:"type"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Severity string `json:"severity"`
+	Time     int64  `json:"time"`
+}
+
+// PostEvent 外部服务推送一条事件，需使用接入密钥（Bearer 或 HMAC 签名）。
+// 与系统日志、QQ 事件一样经由 Publisher 保存并实时推送
+func PostEvent(pub *eventlog.Publisher, guard *ingest.Guard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, body, ok := authenticateIngest(c, guard)
+		if !ok {
+			return
+		}
+		var req ingestEvent
+		if err := json.Unmarshal(body, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid json"})
+			return
+		}
+		events, ok := checkIngestEvents(c, guard, key, []ingestEvent{req})
+		if !ok {
+			return
+		}
+		if err := pub.Publish(events[0]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "id": events[0].ID})
+	}
+}
+
+// PostEventBatch 外部服务批量推送事件（JSON 数组，最多 500 条），在一个事务中写入；
+// 任一条校验失败则整批拒绝，便于调用方原样重试
+func PostEventBatch(pub *eventlog.Publisher, guard *ingest.Guard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, body, ok := authenticateIngest(c, guard)
+		if !ok {
+			return
+		}
+		var reqs []ingestEvent
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "body must be a json array of events"})
+			return
+		}
+		if len(reqs) == 0 || len(reqs) > maxIngestBatch {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": fmt.Sprintf("batch must contain 1 to %d events", maxIngestBatch)})
+			return
+		}
+		events, ok := checkIngestEvents(c, guard, key, reqs)
+		if !ok {
+			return
+		}
+		if err := pub.PublishAll(events); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		ids := make([]int64, len(events))
+		for i, e := range events {
+			ids[i] = e.ID
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "count": len(ids), "ids": ids})
+	}
+}
+
+// authenticateIngest 校验接入密钥并读取请求体，失败时直接写出错误响应
+func authenticateIngest(c *gin.Context, guard *ingest.Guard) (*model.IngestKey, []byte, bool) {
+	key, body, err := guard.Authenticate(c.Request)
+	if err != nil {
+		abortIngest(c, err)
+		return nil, nil, false
+	}
+	return key, body, true
+}
+
+// checkIngestEvents 补全默认值，校验必填项和密钥白名单，最后按事件数扣减限流额度
+func checkIngestEvents(c *gin.Context, guard *ingest.Guard, key *model.IngestKey, reqs []ingestEvent) ([]*model.Event, bool) {
+	events := make([]*model.Event, 0, len(reqs))
+	for i, req := range reqs {
+		prefix := ""
+		if len(reqs) > 1 {
+			prefix = fmt.Sprintf("event %d: ", i)
+		}
+		if req.Summary == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": prefix + "summary required"})
+			return nil, false
+		}
 		if req.Source == "" {
 			req.Source = "openclaw"
 		}
 		if req.Type == "" {
 			req.Type = "openclaw.action"
 		}
-
-		id, err := model.AddEvent(db, &model.Event{
-			Source:  req.Source,
-			Type:    req.Type,
-			Summary: req.Summary,
-			Detail:  req.Detail,
+		if req.Severity != "" && req.Severity != model.SeverityInfo && req.Severity != model.SeverityHigh {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": prefix + "severity must be info or high"})
+			return nil, false
+		}
+		if err := ingest.CheckTime(req.Time); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": prefix + err.Error()})
+			return nil, false
+		}
+		if err := ingest.Permit(key, req.Source, req.Type); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"ok": false, "error": prefix + err.Error()})
+			return nil, false
+		}
+		events = append(events, &model.Event{
+			Time:     req.Time,
+			Source:   req.Source,
+			Type:     req.Type,
+			Summary:  req.Summary,
+			Detail:   req.Detail,
+			Severity: req.Severity,
 		})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
-			return
+	}
+	if wait, err := guard.Allow(key, len(events)); err != nil {
+		if wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
 		}
+		abortIngest(c, err)
+		return nil, false
+	}
+	return events, true
+}
 
-		c.JSON(http.StatusOK, gin.H{"ok": true, "id": id})
+func abortIngest(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if e, ok := err.(*ingest.Error); ok {
+		status = e.Status
 	}
+	c.JSON(status, gin.H{"ok": false, "error": err.Error()})
 }
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// exportBatch 导出时每批读取的事件数，批次之间释放数据库连接
+const exportBatch = 1000
+
+// importBatch 导入时每个事务写入的事件数
+const importBatch = 500
+
+// maxImportLine 导入时单行 JSON 的最大长度
+const maxImportLine = 4 << 20
+
+var exportFormats = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"jsonl":  "application/jsonl; charset=utf-8",
+	"ndjson": "application/x-ndjson; charset=utf-8",
+}
+
+// ExportEvents 按 /api/events 的筛选条件流式导出事件（?format=csv|jsonl|ndjson&order=desc|asc&limit=）。
+// 分批按游标读取，边读边以 chunked 方式写出，不在内存中缓存全部结果
+func ExportEvents(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "jsonl")
+		contentType, ok := exportFormats[format]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "format must be csv, jsonl or ndjson"})
+			return
+		}
+		ascending := c.Query("order") == "asc"
+		max, _ := strconv.Atoi(c.Query("limit"))
+
+		q := eventFilter(c)
+		q.SortByTime = true
+		q.SkipCount = true
+		q.Ascending = ascending
+
+		filename := fmt.Sprintf("clawpanel-events-%s.%s", time.Now().Format("20060102-150405"), format)
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+
+		var csvw *csv.Writer
+		var enc *json.Encoder
+		if format == "csv" {
+			// UTF-8 BOM，Excel 打开中文不乱码
+			c.Writer.WriteString("\ufeff")
+			csvw = csv.NewWriter(c.Writer)
+			csvw.Write([]string{"id", "time", "datetime", "source", "type", "severity", "summary", "detail"})
+		} else {
+			enc = json.NewEncoder(c.Writer)
+			enc.SetEscapeHTML(false)
+		}
+
+		written := 0
+		for {
+			batch := exportBatch
+			if max > 0 && max-written < batch {
+				batch = max - written
+			}
+			if batch <= 0 {
+				break
+			}
+			q.Limit = batch
+			events, _, err := model.QueryEvents(db, q)
+			if err != nil {
+				// 响应头已发送，只能中断输出
+				c.Error(err)
+				break
+			}
+			for _, e := range events {
+				e.Snippet = ""
+				if csvw != nil {
+					csvw.Write([]string{
+						strconv.FormatInt(e.ID, 10),
+						strconv.FormatInt(e.Time, 10),
+						time.UnixMilli(e.Time).Format(time.RFC3339),
+						e.Source, e.Type, e.Severity, e.Summary, e.Detail,
+					})
+				} else {
+					enc.Encode(e)
+				}
+			}
+			if csvw != nil {
+				csvw.Flush()
+			}
+			c.Writer.Flush()
+
+			written += len(events)
+			if len(events) < batch || c.Request.Context().Err() != nil {
+				break
+			}
+			last := model.CursorOf(events[len(events)-1])
+			if ascending {
+				q.Later = last
+			} else {
+				q.Before = last
+			}
+		}
+	}
+}
+
+// ImportEvents 导入 JSONL / NDJSON 格式的事件（导出文件原样上传）。请求体为文件内容，
+// 或 multipart 表单的 file 字段。逐行解析、分批写入，返回导入、跳过和出错的行数
+func ImportEvents(db *sql.DB, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body io.Reader = c.Request.Body
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			file, _, err := c.Request.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "file required"})
+				return
+			}
+			defer file.Close()
+			body = file
+		}
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 64<<10), maxImportLine)
+
+		var imported, skipped, failed int
+		lineErrors := []string{}
+		addError := func(line int, msg string) {
+			failed++
+			if len(lineErrors) < 20 {
+				lineErrors = append(lineErrors, fmt.Sprintf("line %d: %s", line, msg))
+			}
+		}
+
+		batch := make([]model.Event, 0, importBatch)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			n, dup, err := model.ImportEvents(db, batch)
+			imported += n
+			skipped += dup
+			batch = batch[:0]
+			return err
+		}
+
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+			if text == "" {
+				continue
+			}
+			var e model.Event
+			if err := json.Unmarshal([]byte(text), &e); err != nil {
+				addError(line, "invalid json")
+				continue
+			}
+			if e.Source == "" || e.Type == "" || e.Summary == "" || e.Time <= 0 {
+				addError(line, "time, source, type and summary required")
+				continue
+			}
+			e.Snippet = ""
+			batch = append(batch, e)
+			if len(batch) >= importBatch {
+				if err := flush(); err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error(), "imported": imported})
+					return
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			addError(line+1, err.Error())
+		}
+		if err := flush(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error(), "imported": imported})
+			return
+		}
+
+		if len(sysLog) > 0 && sysLog[0] != nil && imported > 0 {
+			sysLog[0].Log("system", "events.imported", fmt.Sprintf("导入活动日志 %d 条（跳过重复 %d 条，失败 %d 行）", imported, skipped, failed))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"ok":       true,
+			"imported": imported,
+			"skipped":  skipped,
+			"failed":   failed,
+			"errors":   lineErrors,
+		})
+	}
+}
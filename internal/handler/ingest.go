@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/ingest"
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// ingestKeyRequest 新建 / 修改接入密钥的请求体。新建时省略的限制使用默认值，
+// 修改时省略的字段保留原值
+type ingestKeyRequest struct {
+	Name      *string   `json:"name"`
+	Mode      *string   `json:"mode"`
+	Sources   *[]string `json:"sources"`
+	Types     *[]string `json:"types"`
+	RateLimit *int      `json:"rateLimit"`
+	MaxBytes  *int      `json:"maxBytes"`
+	Enabled   *bool     `json:"enabled"`
+}
+
+// GetIngestKeys 获取接入密钥列表（不返回 secret）
+func GetIngestKeys(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := model.GetIngestKeys(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "keys": keys})
+	}
+}
+
+// CreateIngestKey 新建接入密钥，secret 只在创建和轮换时返回一次
+func CreateIngestKey(db *sql.DB, guard *ingest.Guard, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		saveIngestKey(c, db, guard, 0, sysLog)
+	}
+}
+
+// UpdateIngestKey 修改接入密钥的名称、认证方式和限制，不改变 secret
+func UpdateIngestKey(db *sql.DB, guard *ingest.Guard, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		saveIngestKey(c, db, guard, id, sysLog)
+	}
+}
+
+// DeleteIngestKey 删除接入密钥，使用该密钥的请求立即失效
+func DeleteIngestKey(db *sql.DB, guard *ingest.Guard, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		if err := model.DeleteIngestKey(db, id); err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "key not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		guard.Reload()
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			sysLog[0].Log("system", "ingest.key.deleted", fmt.Sprintf("接入密钥 #%d 已删除", id))
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// RotateIngestKey 重新生成 secret，旧 secret 立即失效
+func RotateIngestKey(db *sql.DB, guard *ingest.Guard, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		key, err := model.GetIngestKey(db, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "key not found"})
+			return
+		}
+		key.Secret = ingest.NewSecret()
+		if err := model.SaveIngestKey(db, key); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		guard.Reload()
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			sysLog[0].Log("system", "ingest.key.rotated", fmt.Sprintf("接入密钥已轮换: %s (%s)", key.Name, key.KeyID))
+		}
+		c.JSON(http.StatusOK, ingestKeyResponse(key))
+	}
+}
+
+// ingestKeyResponse 带 secret 的响应，Bearer 模式额外给出完整的 token
+func ingestKeyResponse(key *model.IngestKey) gin.H {
+	resp := gin.H{"ok": true, "key": key, "secret": key.Secret}
+	if key.Mode == model.IngestBearer {
+		resp["token"] = key.KeyID + "." + key.Secret
+	}
+	return resp
+}
+
+func saveIngestKey(c *gin.Context, db *sql.DB, guard *ingest.Guard, id int64, sysLog []*eventlog.SystemLogger) {
+	var req ingestKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	key := &model.IngestKey{ID: id, Enabled: true, KeyID: ingest.NewKeyID(), Secret: ingest.NewSecret()}
+	if id > 0 {
+		existing, err := model.GetIngestKey(db, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "key not found"})
+			return
+		}
+		key = existing
+	}
+	if req.Name != nil {
+		key.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Mode != nil {
+		key.Mode = *req.Mode
+	}
+	if req.Sources != nil {
+		key.Sources = trimList(*req.Sources)
+	}
+	if req.Types != nil {
+		key.Types = trimList(*req.Types)
+	}
+	if req.RateLimit != nil {
+		key.RateLimit = *req.RateLimit
+	}
+	if req.MaxBytes != nil {
+		key.MaxBytes = *req.MaxBytes
+	}
+	if req.Enabled != nil {
+		key.Enabled = *req.Enabled
+	}
+
+	if key.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "name required"})
+		return
+	}
+	if key.Mode == "" {
+		key.Mode = model.IngestBearer
+	}
+	if key.Mode != model.IngestBearer && key.Mode != model.IngestHMAC {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "mode must be bearer or hmac"})
+		return
+	}
+	if key.RateLimit <= 0 {
+		key.RateLimit = model.DefaultIngestRateLimit
+	}
+	if key.MaxBytes <= 0 {
+		key.MaxBytes = model.DefaultIngestMaxBytes
+	}
+	for _, p := range append(append([]string{}, key.Sources...), key.Types...) {
+		if _, err := path.Match(p, ""); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": fmt.Sprintf("invalid pattern %q", p)})
+			return
+		}
+	}
+
+	if err := model.SaveIngestKey(db, key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	guard.Reload()
+	if len(sysLog) > 0 && sysLog[0] != nil {
+		action := "updated"
+		if id == 0 {
+			action = "created"
+		}
+		sysLog[0].Log("system", "ingest.key."+action, fmt.Sprintf("接入密钥已保存: %s (%s)", key.Name, key.KeyID))
+	}
+	if id == 0 {
+		c.JSON(http.StatusOK, ingestKeyResponse(key))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "key": key})
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// GetConversations 列出已记录消息的群聊/私聊会话（?type=group|private&selfId=）
+func GetConversations(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msgType := c.Query("type")
+		if msgType != "" && msgType != "group" && msgType != "private" {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "type must be group or private"})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if limit <= 0 || limit > 500 {
+			limit = 100
+		}
+		list, err := model.GetConversations(db, msgType, c.Query("selfId"), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "conversations": list})
+	}
+}
+
+// GetConversationMessages 按会话倒序分页浏览消息，?before= 传上一页返回的 nextCursor
+func GetConversationMessages(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msgType := c.Param("type")
+		if msgType != "group" && msgType != "private" {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "type must be group or private"})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if limit <= 0 || limit > 200 {
+			limit = 50
+		}
+		before, _ := strconv.ParseInt(c.Query("before"), 10, 64)
+
+		// 多取一条判断是否还有下一页
+		messages, err := model.GetConversation(db, msgType, c.Param("peer"), c.Query("selfId"), before, limit+1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		hasMore := len(messages) > limit
+		nextCursor := ""
+		if hasMore {
+			messages = messages[:limit]
+			nextCursor = strconv.FormatInt(messages[limit-1].ID, 10)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"ok":         true,
+			"messages":   messages,
+			"hasMore":    hasMore,
+			"nextCursor": nextCursor,
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -115,7 +116,7 @@ func GetSoftwareList(cfg *config.Config) gin.HandlerFunc {
 		})
 
 		// NapCat (QQ)
-		napcatExists, napcatStatus := getDockerContainerStatus("openclaw-qq")
+		napcatExists, napcatStatus := getDockerContainerStatus(cfg.GetEndpoints().QQContainer)
 		napcatVer := ""
 		if napcatExists {
 			napcatVer = "Docker"
@@ -127,7 +128,7 @@ func GetSoftwareList(cfg *config.Config) gin.HandlerFunc {
 		})
 
 		// WeChat Bot
-		wechatExists, wechatStatus := getDockerContainerStatus("openclaw-wechat")
+		wechatExists, wechatStatus := getDockerContainerStatus(cfg.GetEndpoints().WechatContainer)
 		wechatVer := ""
 		if wechatExists {
 			wechatVer = "Docker"
@@ -198,6 +199,7 @@ func DetectOpenClawInstances(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// 3. Docker container
+		endpoints := cfg.GetEndpoints()
 		dockerOut := detectCmd("docker", "ps", "-a", "--filter", "name=openclaw", "--format", "{{.Names}}|{{.Status}}|{{.Image}}")
 		if dockerOut != "" {
 			for _, line := range strings.Split(dockerOut, "\n") {
@@ -210,7 +212,7 @@ func DetectOpenClawInstances(cfg *config.Config) gin.HandlerFunc {
 						image = parts[2]
 					}
 					// Skip our management containers
-					if name == "openclaw-qq" || name == "openclaw-wechat" {
+					if name == endpoints.QQContainer || name == endpoints.WechatContainer {
 						continue
 					}
 					running := strings.HasPrefix(status, "Up")
@@ -271,6 +273,7 @@ func InstallSoftware(cfg *config.Config, tm *taskman.Manager) gin.HandlerFunc {
 
 		var script string
 		var taskName string
+		var err error
 
 		switch req.Software {
 		case "nodejs":
@@ -354,16 +357,20 @@ openclaw init 2>/dev/null || true
 `
 		case "napcat":
 			taskName = "安装 NapCat (QQ个人号)"
-			script = buildNapCatInstallScript(cfg)
+			script, err = buildNapCatInstallScript(cfg)
 
 		case "wechat":
 			taskName = "安装微信机器人"
-			script = buildWeChatInstallScript(cfg)
+			script, err = buildWeChatInstallScript(cfg)
 
 		default:
 			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "不支持的软件: " + req.Software})
 			return
 		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
 
 		task := tm.CreateTask(taskName, "install_"+req.Software)
 
@@ -412,9 +419,24 @@ func getSudoPass(cfg *config.Config) string {
 	return strings.TrimSpace(string(data))
 }
 
-func buildNapCatInstallScript(cfg *config.Config) string {
+// containerNameRe Docker 容器名允许的字符，安装脚本直接拼接容器名，不符合的拒绝执行
+var containerNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+func checkContainerName(name string) error {
+	if !containerNameRe.MatchString(name) {
+		return fmt.Errorf("容器名不合法: %q", name)
+	}
+	return nil
+}
+
+func buildNapCatInstallScript(cfg *config.Config) (string, error) {
+	container := cfg.GetEndpoints().QQContainer
+	if err := checkContainerName(container); err != nil {
+		return "", err
+	}
 	return fmt.Sprintf(`
 set -e
+CONTAINER=%s
 echo "📦 安装 NapCat (QQ个人号) Docker 容器..."
 
 if ! command -v docker &>/dev/null; then
@@ -423,10 +445,10 @@ if ! command -v docker &>/dev/null; then
 fi
 
 # Check if already exists
-if docker inspect openclaw-qq &>/dev/null; then
-  echo "⚠️ openclaw-qq 容器已存在，正在重新创建..."
-  docker stop openclaw-qq 2>/dev/null || true
-  docker rm openclaw-qq 2>/dev/null || true
+if docker inspect "$CONTAINER" &>/dev/null; then
+  echo "⚠️ $CONTAINER 容器已存在，正在重新创建..."
+  docker stop "$CONTAINER" 2>/dev/null || true
+  docker rm "$CONTAINER" 2>/dev/null || true
 fi
 
 echo "📥 拉取 NapCat 镜像..."
@@ -434,7 +456,7 @@ docker pull mlikiowa/napcat-docker:latest
 
 echo "🔧 创建容器..."
 docker run -d \
-  --name openclaw-qq \
+  --name "$CONTAINER" \
   --restart unless-stopped \
   -p 3000:3000 \
   -p 3001:3001 \
@@ -453,7 +475,7 @@ sleep 5
 
 # Configure OneBot11 WebSocket + HTTP
 echo "🔧 配置 OneBot11 (WS + HTTP)..."
-docker exec openclaw-qq bash -c 'cat > /app/napcat/config/onebot11.json << OBEOF
+docker exec "$CONTAINER" bash -c 'cat > /app/napcat/config/onebot11.json << OBEOF
 {
   "network": {
     "websocketServers": [{
@@ -488,7 +510,7 @@ docker exec openclaw-qq bash -c 'cat > /app/napcat/config/onebot11.json << OBEOF
 OBEOF'
 
 # Configure WebUI
-docker exec openclaw-qq bash -c 'cat > /app/napcat/config/webui.json << WUEOF
+docker exec "$CONTAINER" bash -c 'cat > /app/napcat/config/webui.json << WUEOF
 {
   "host": "0.0.0.0",
   "port": 6099,
@@ -499,12 +521,17 @@ WUEOF'
 
 echo "✅ NapCat (QQ个人号) 安装完成"
 echo "📝 请在通道管理中配置 QQ 并扫码登录"
-`, cfg.OpenClawDir, cfg.OpenClawWork)
+`, container, cfg.OpenClawDir, cfg.OpenClawWork), nil
 }
 
-func buildWeChatInstallScript(cfg *config.Config) string {
-	return `
+func buildWeChatInstallScript(cfg *config.Config) (string, error) {
+	container := cfg.GetEndpoints().WechatContainer
+	if err := checkContainerName(container); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`
 set -e
+CONTAINER=%s
 echo "📦 安装微信机器人 Docker 容器..."
 
 if ! command -v docker &>/dev/null; then
@@ -513,10 +540,10 @@ if ! command -v docker &>/dev/null; then
 fi
 
 # Check if already exists
-if docker inspect openclaw-wechat &>/dev/null; then
-  echo "⚠️ openclaw-wechat 容器已存在，正在重新创建..."
-  docker stop openclaw-wechat 2>/dev/null || true
-  docker rm openclaw-wechat 2>/dev/null || true
+if docker inspect "$CONTAINER" &>/dev/null; then
+  echo "⚠️ $CONTAINER 容器已存在，正在重新创建..."
+  docker stop "$CONTAINER" 2>/dev/null || true
+  docker rm "$CONTAINER" 2>/dev/null || true
 fi
 
 echo "📥 拉取 wechatbot-webhook 镜像..."
@@ -524,7 +551,7 @@ docker pull dannicool/docker-wechatbot-webhook:latest
 
 echo "🔧 创建容器..."
 docker run -d \
-  --name openclaw-wechat \
+  --name "$CONTAINER" \
   --restart unless-stopped \
   -p 3002:3001 \
   -e LOGIN_API_TOKEN=clawpanel-wechat \
@@ -540,5 +567,5 @@ sleep 3
 
 echo "✅ 微信机器人安装完成"
 echo "📝 请在通道管理中配置微信并扫码登录"
-`
+`, container), nil
 }
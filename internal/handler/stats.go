@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// statsDefaultRange 未指定 since 时统计最近 7 天
+const statsDefaultRange = 7 * 24 * time.Hour
+
+// statsFilter 解析统计查询参数（since / until 为毫秒时间戳，channel、selfId、groupId 可选）
+func statsFilter(c *gin.Context) (model.StatsFilter, bool) {
+	f := model.StatsFilter{
+		Channel: c.Query("channel"),
+		SelfID:  c.Query("selfId"),
+		GroupID: c.Query("groupId"),
+	}
+	f.Since, _ = strconv.ParseInt(c.Query("since"), 10, 64)
+	f.Until, _ = strconv.ParseInt(c.Query("until"), 10, 64)
+	if f.Until <= 0 {
+		f.Until = time.Now().UnixMilli()
+	}
+	if f.Since <= 0 {
+		f.Since = f.Until - statsDefaultRange.Milliseconds()
+	}
+	if f.Since >= f.Until {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "since must be earlier than until"})
+		return f, false
+	}
+	return f, true
+}
+
+func statsLimit(c *gin.Context) int {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+	return limit
+}
+
+// GetStatsOverview 收发消息总数、机器人回复率、各通道汇总及活跃群 / 用户数
+func GetStatsOverview(db *sql.DB, roller *eventlog.StatsRoller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, ok := statsFilter(c)
+		if !ok {
+			return
+		}
+		roller.Refresh()
+		channels, err := model.GetChannelStats(db, f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		groups, users, err := model.CountActive(db, f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		var received, sent int64
+		for _, ch := range channels {
+			received += ch.Received
+			sent += ch.Sent
+		}
+		// 回复率 = 发出 / 收到
+		replyRatio := 0.0
+		if received > 0 {
+			replyRatio = float64(sent) / float64(received)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"ok":           true,
+			"since":        f.Since,
+			"until":        f.Until,
+			"received":     received,
+			"sent":         sent,
+			"replyRatio":   replyRatio,
+			"channels":     channels,
+			"activeGroups": groups,
+			"activeUsers":  users,
+		})
+	}
+}
+
+// GetStatsMessages 收发消息时间序列（?interval=hour|day）
+func GetStatsMessages(db *sql.DB, roller *eventlog.StatsRoller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		interval := c.DefaultQuery("interval", "hour")
+		if interval != "hour" && interval != "day" {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "interval must be hour or day"})
+			return
+		}
+		f, ok := statsFilter(c)
+		if !ok {
+			return
+		}
+		// 按小时最多返回 31 天，避免一次生成过多的点
+		if interval == "hour" && f.Until-f.Since > (31*24*time.Hour).Milliseconds() {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "range too large for hourly interval, use interval=day"})
+			return
+		}
+		roller.Refresh()
+		points, err := model.GetStatsSeries(db, f, interval == "day")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "interval": interval, "points": points})
+	}
+}
+
+// GetStatsGroups 消息最多的群（?limit=）
+func GetStatsGroups(db *sql.DB, roller *eventlog.StatsRoller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, ok := statsFilter(c)
+		if !ok {
+			return
+		}
+		roller.Refresh()
+		groups, err := model.GetTopGroups(db, f, statsLimit(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "groups": groups})
+	}
+}
+
+// GetStatsUsers 发言最多的用户（?limit=&groupId=），按自然日统计
+func GetStatsUsers(db *sql.DB, roller *eventlog.StatsRoller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, ok := statsFilter(c)
+		if !ok {
+			return
+		}
+		roller.Refresh()
+		users, err := model.GetTopUsers(db, f, statsLimit(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "users": users})
+	}
+}
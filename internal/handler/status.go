@@ -3,7 +3,6 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
-	"io"
 	"net/http"
 	"os"
 	"os/exec"
@@ -14,27 +13,20 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/channel"
 	"github.com/zhaoxinyi02/ClawPanel/internal/config"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
 	"github.com/zhaoxinyi02/ClawPanel/internal/process"
 )
 
 var startTime = time.Now()
 
 // GetStatus 获取系统状态总览
-func GetStatus(db *sql.DB, cfg *config.Config, procMgr *process.Manager) gin.HandlerFunc {
+func GetStatus(db *sql.DB, cfg *config.Config, procMgr *process.Manager, listeners eventlog.Listeners) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ocConfig, _ := cfg.ReadOpenClawJSON()
 
 		// 提取已启用的通道
-		channelLabels := map[string]string{
-			"qq": "QQ (NapCat)", "wechat": "微信", "whatsapp": "WhatsApp",
-			"telegram": "Telegram", "discord": "Discord", "irc": "IRC",
-			"slack": "Slack", "signal": "Signal", "googlechat": "Google Chat",
-			"webchat": "WebChat", "feishu": "飞书 / Lark", "qqbot": "QQ 官方机器人",
-			"dingtalk": "钉钉", "wecom": "企业微信", "msteams": "Microsoft Teams",
-			"mattermost": "Mattermost", "line": "LINE", "matrix": "Matrix", "twitch": "Twitch",
-		}
-
 		type enabledChannel struct {
 			ID    string `json:"id"`
 			Label string `json:"label"`
@@ -48,11 +40,7 @@ func GetStatus(db *sql.DB, cfg *config.Config, procMgr *process.Manager) gin.Han
 				for id, conf := range ch {
 					if m, ok := conf.(map[string]interface{}); ok {
 						if enabled, _ := m["enabled"].(bool); enabled {
-							label := channelLabels[id]
-							if label == "" {
-								label = id
-							}
-							channels = append(channels, enabledChannel{ID: id, Label: label, Type: "builtin"})
+							channels = append(channels, enabledChannel{ID: id, Label: channel.Label(id), Type: "builtin"})
 						}
 					}
 				}
@@ -74,11 +62,7 @@ func GetStatus(db *sql.DB, cfg *config.Config, procMgr *process.Manager) gin.Han
 						}
 						if m, ok := conf.(map[string]interface{}); ok {
 							if enabled, _ := m["enabled"].(bool); enabled {
-								label := channelLabels[id]
-								if label == "" {
-									label = id
-								}
-								channels = append(channels, enabledChannel{ID: id, Label: label, Type: "plugin"})
+								channels = append(channels, enabledChannel{ID: id, Label: channel.Label(id), Type: "plugin"})
 							}
 						}
 					}
@@ -108,43 +92,44 @@ func GetStatus(db *sql.DB, cfg *config.Config, procMgr *process.Manager) gin.Han
 		var memStats runtime.MemStats
 		runtime.ReadMemStats(&memStats)
 
+		// 各 NapCat 实例（QQ 账号）状态，qq 通道即第一个实例，复用同一次查询结果
+		napcatInstances, qqStatus := queryNapcatInstances()
+
+		// 各通道适配器状态
+		channelStatus := map[string]*channel.Status{}
+		for _, a := range channel.All() {
+			if qq, ok := a.(*channel.QQAdapter); ok {
+				if st := qqStatus[qq.InstanceID()]; st != nil {
+					channelStatus[a.ID()] = st
+					continue
+				}
+			}
+			st, err := a.Status()
+			if err != nil {
+				st.Error = err.Error()
+			}
+			channelStatus[a.ID()] = st
+		}
+
 		// NapCat 登录状态
 		napcatInfo := gin.H{"connected": false}
-		if loginR, err := napcatApiCallSafe(cfg, "POST", "/api/QQLogin/CheckLoginStatus", nil); err == nil {
-			if data, ok := loginR["data"].(map[string]interface{}); ok {
-				if isLogin, _ := data["isLogin"].(bool); isLogin {
-					napcatInfo["connected"] = true
-					// 获取登录信息
-					if infoR, err := napcatApiCallSafe(cfg, "POST", "/api/QQLogin/GetQQLoginInfo", nil); err == nil {
-						if infoData, ok := infoR["data"].(map[string]interface{}); ok {
-							napcatInfo["nickname"], _ = infoData["nick"].(string)
-							if uin, ok := infoData["uin"].(string); ok {
-								napcatInfo["selfId"] = uin
-							}
-						}
-					}
-					// 获取群数和好友数 (通过 OneBot11 HTTP API)
-					if groupR, err := onebotApiCallSafe("POST", "/get_group_list", nil); err == nil {
-						if groupData, ok := groupR["data"].([]interface{}); ok {
-							napcatInfo["groupCount"] = len(groupData)
-						}
-					}
-					if friendR, err := onebotApiCallSafe("POST", "/get_friend_list", nil); err == nil {
-						if friendData, ok := friendR["data"].([]interface{}); ok {
-							napcatInfo["friendCount"] = len(friendData)
-						}
-					}
-				}
+		if st := channelStatus["qq"]; st != nil && st.LoggedIn {
+			napcatInfo["connected"] = true
+			napcatInfo["nickname"] = st.Nickname
+			if st.SelfID != "" {
+				napcatInfo["selfId"] = st.SelfID
+			}
+			for k, v := range st.Extra {
+				napcatInfo[k] = v
 			}
 		}
 
 		// WeChat 状态
 		wechatInfo := gin.H{"connected": false, "loggedIn": false}
-		if wechatR, err := wechatApiCallSafe(cfg, "GET", "/loginCheck", nil); err == nil {
-			if success, ok := wechatR["success"].(bool); ok && success {
-				wechatInfo["connected"] = true
-				wechatInfo["loggedIn"] = true
-			}
+		if st := channelStatus["wechat"]; st != nil {
+			wechatInfo["connected"] = st.Connected
+			wechatInfo["loggedIn"] = st.LoggedIn
+			wechatInfo["name"] = st.Nickname
 		}
 
 		c.JSON(http.StatusOK, gin.H{
@@ -155,7 +140,10 @@ func GetStatus(db *sql.DB, cfg *config.Config, procMgr *process.Manager) gin.Han
 				"enabledChannels": channels,
 			},
 			"napcat":  napcatInfo,
+			"napcatInstances": napcatInstances,
+			"onebot":          onebotConnections(listeners),
 			"wechat":  wechatInfo,
+			"channels": channelStatus,
 			"process": procStatus,
 			"admin": gin.H{
 				"uptime":   int64(time.Since(startTime).Seconds()),
@@ -168,88 +156,6 @@ func GetStatus(db *sql.DB, cfg *config.Config, procMgr *process.Manager) gin.Han
 	}
 }
 
-// napcatApiCallSafe calls NapCat API with a short timeout, returns nil on error
-func napcatApiCallSafe(cfg *config.Config, method, path string, body interface{}) (map[string]interface{}, error) {
-	cred := napcatAuth(cfg)
-	var bodyReader io.Reader
-	if body != nil {
-		data, _ := json.Marshal(body)
-		bodyReader = strings.NewReader(string(data))
-	}
-	req, err := http.NewRequest(method, "http://127.0.0.1:6099"+path, bodyReader)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	if cred != "" {
-		req.Header.Set("Authorization", "Bearer "+cred)
-	}
-	client := &http.Client{Timeout: 3 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	data, _ := io.ReadAll(resp.Body)
-	var result map[string]interface{}
-	json.Unmarshal(data, &result)
-	return result, nil
-}
-
-// wechatApiCallSafe calls WeChat API with a short timeout
-func wechatApiCallSafe(cfg *config.Config, method, path string, body interface{}) (map[string]interface{}, error) {
-	adminCfg := loadAdminConfig(cfg)
-	wechatUrl := "http://127.0.0.1:3002"
-	wechatToken := "openclaw-wechat"
-	if wc, ok := adminCfg["wechat"].(map[string]interface{}); ok {
-		if u, ok := wc["apiUrl"].(string); ok && u != "" {
-			wechatUrl = u
-		}
-		if t, ok := wc["token"].(string); ok && t != "" {
-			wechatToken = t
-		}
-	}
-	req, err := http.NewRequest(method, wechatUrl+path, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+wechatToken)
-	client := &http.Client{Timeout: 3 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	data, _ := io.ReadAll(resp.Body)
-	var result map[string]interface{}
-	json.Unmarshal(data, &result)
-	return result, nil
-}
-
-// onebotApiCallSafe calls OneBot11 HTTP API (port 3000) with a short timeout
-func onebotApiCallSafe(method, path string, body interface{}) (map[string]interface{}, error) {
-	var bodyReader io.Reader
-	if body != nil {
-		data, _ := json.Marshal(body)
-		bodyReader = strings.NewReader(string(data))
-	}
-	req, err := http.NewRequest(method, "http://127.0.0.1:3000"+path, bodyReader)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 3 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	data, _ := io.ReadAll(resp.Body)
-	var result map[string]interface{}
-	json.Unmarshal(data, &result)
-	return result, nil
-}
-
 // runCmd runs a command and returns trimmed stdout, or fallback on error
 func runCmd(name string, args ...string) string {
 	cmd := exec.Command(name, args...)
@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhaoxinyi02/ClawPanel/internal/eventlog"
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+	"github.com/zhaoxinyi02/ClawPanel/internal/webhook"
+)
+
+// webhookRequest 新建 / 修改 Webhook 的请求体，secret 省略时保留原值，传空字符串则清除
+type webhookRequest struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Secret  *string  `json:"secret"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// GetWebhooks 获取外发 Webhook 列表（不返回 secret）
+func GetWebhooks(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hooks, err := model.GetWebhooks(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "webhooks": hooks})
+	}
+}
+
+// CreateWebhook 新建 Webhook
+func CreateWebhook(db *sql.DB, dispatcher *webhook.Dispatcher, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		saveWebhook(c, db, dispatcher, 0, sysLog)
+	}
+}
+
+// UpdateWebhook 修改 Webhook
+func UpdateWebhook(db *sql.DB, dispatcher *webhook.Dispatcher, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		saveWebhook(c, db, dispatcher, id, sysLog)
+	}
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func DeleteWebhook(db *sql.DB, dispatcher *webhook.Dispatcher, sysLog ...*eventlog.SystemLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		if err := model.DeleteWebhook(db, id); err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "webhook not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		dispatcher.Reload()
+		if len(sysLog) > 0 && sysLog[0] != nil {
+			sysLog[0].Log("system", "webhook.deleted", fmt.Sprintf("Webhook #%d 已删除", id))
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+// TestWebhook 向 Webhook 投递一条 webhook.test 测试事件，结果可在投递记录中查看
+func TestWebhook(db *sql.DB, dispatcher *webhook.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := idParam(c)
+		if !ok {
+			return
+		}
+		hook, err := model.GetWebhook(db, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "webhook not found"})
+			return
+		}
+		delivery, err := dispatcher.Test(*hook)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "delivery": delivery})
+	}
+}
+
+// GetWebhookDeliveries 查询投递记录（?webhookId=&status=pending|success|failed&limit=&offset=）
+func GetWebhookDeliveries(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.Query("status")
+		if status != "" && status != model.DeliveryPending && status != model.DeliverySuccess && status != model.DeliveryFailed {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "status must be pending, success or failed"})
+			return
+		}
+		hookID, _ := strconv.ParseInt(c.Query("webhookId"), 10, 64)
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		list, total, err := model.GetDeliveries(db, hookID, status, limit, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "deliveries": list, "total": total})
+	}
+}
+
+// RetryWebhookDelivery 将投递重新放回队列立即发送
+func RetryWebhookDelivery(db *sql.DB, dispatcher *webhook.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid id"})
+			return
+		}
+		if err := model.RetryDelivery(db, id); err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "delivery not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		dispatcher.Wake()
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	}
+}
+
+func idParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func saveWebhook(c *gin.Context, db *sql.DB, dispatcher *webhook.Dispatcher, id int64, sysLog []*eventlog.SystemLogger) {
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+
+	hook := &model.Webhook{ID: id, Enabled: true}
+	if id > 0 {
+		existing, err := model.GetWebhook(db, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "webhook not found"})
+			return
+		}
+		hook = existing
+	}
+	hook.Name = strings.TrimSpace(req.Name)
+	hook.URL = strings.TrimSpace(req.URL)
+	hook.Events = trimList(req.Events)
+	if req.Secret != nil {
+		hook.Secret = *req.Secret
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+
+	if hook.Name == "" {
+		hook.Name = hook.URL
+	}
+	if !strings.HasPrefix(hook.URL, "http://") && !strings.HasPrefix(hook.URL, "https://") {
+		c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "url must be http(s)"})
+		return
+	}
+	for _, p := range hook.Events {
+		if _, err := path.Match(p, ""); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": fmt.Sprintf("invalid event pattern %q", p)})
+			return
+		}
+	}
+
+	if err := model.SaveWebhook(db, hook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"ok": false, "error": err.Error()})
+		return
+	}
+	dispatcher.Reload()
+	if len(sysLog) > 0 && sysLog[0] != nil {
+		action := "updated"
+		if id == 0 {
+			action = "created"
+		}
+		sysLog[0].Log("system", "webhook."+action, fmt.Sprintf("Webhook 已保存: #%d %s", hook.ID, hook.Name))
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "webhook": hook})
+}
@@ -56,6 +56,23 @@ func getWorkspaceDir(cfg *config.Config) string {
 	return workDir
 }
 
+// resolveWorkspaceFile 将工作区相对路径解析为绝对路径，拒绝越界路径和目录
+func resolveWorkspaceFile(cfg *config.Config, relPath string) (string, error) {
+	if relPath == "" {
+		return "", fmt.Errorf("Path required")
+	}
+	wsAbs, _ := filepath.Abs(getWorkspaceDir(cfg))
+	abs, _ := filepath.Abs(filepath.Join(wsAbs, relPath))
+	if abs != wsAbs && !strings.HasPrefix(abs, wsAbs+string(filepath.Separator)) {
+		return "", fmt.Errorf("Invalid path")
+	}
+	info, err := os.Stat(abs)
+	if err != nil || info.IsDir() {
+		return "", fmt.Errorf("File not found: %s", relPath)
+	}
+	return abs, nil
+}
+
 func getWsConfigPath(cfg *config.Config) string {
 	return filepath.Join(cfg.DataDir, "workspace-config.json")
 }
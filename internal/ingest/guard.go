@@ -0,0 +1,296 @@
+package ingest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+	"github.com/zhaoxinyi02/ClawPanel/internal/webhook"
+)
+
+// HMAC 模式的请求头，时间戳与签名沿用外发 Webhook 的格式
+const (
+	HeaderKey       = "X-ClawPanel-Key"
+	HeaderTimestamp = webhook.HeaderTimestamp
+	HeaderSignature = webhook.HeaderSignature
+)
+
+// signatureTolerance 签名时间戳允许的偏差，超出视为重放
+const signatureTolerance = 5 * time.Minute
+
+// touchInterval 最近使用时间的写库间隔
+const touchInterval = time.Minute
+
+// ReservedSources 面板自身产生事件的来源，密钥的 sources 中逐字列出才允许推送（通配符不算）
+var ReservedSources = []string{"system", "qq", "alert"}
+
+// Error 带 HTTP 状态码的接入错误
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func errorf(status int, format string, args ...interface{}) *Error {
+	return &Error{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// bucket 每个密钥的令牌桶，容量为每分钟限额
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	touched time.Time
+}
+
+// Guard 校验 /api/events/log 的接入密钥，并按密钥限流。密钥缓存在内存中，修改后调用 Reload
+type Guard struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	keys    map[string]model.IngestKey // keyId -> 密钥
+	buckets map[string]*bucket
+
+	// HMAC 模式下时间窗口内已使用的签名（keyId:signature -> 失效时间），拒绝重放
+	seenMu     sync.Mutex
+	seen       map[string]time.Time
+	seenPruned time.Time
+}
+
+// NewGuard 创建接入校验器并加载密钥
+func NewGuard(db *sql.DB) *Guard {
+	g := &Guard{db: db, keys: map[string]model.IngestKey{}, buckets: map[string]*bucket{}, seen: map[string]time.Time{}}
+	g.Reload()
+	return g
+}
+
+// Reload 重新加载密钥，保留未删除密钥的限流状态
+func (g *Guard) Reload() {
+	keys, err := model.GetIngestKeys(g.db)
+	if err != nil {
+		log.Printf("[Ingest] 加载接入密钥失败: %v", err)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.keys = make(map[string]model.IngestKey, len(keys))
+	for _, k := range keys {
+		g.keys[k.KeyID] = k
+	}
+	for id := range g.buckets {
+		if _, ok := g.keys[id]; !ok {
+			delete(g.buckets, id)
+		}
+	}
+}
+
+// Authenticate 识别请求使用的密钥并读取请求体（不超过密钥的 maxBytes），HMAC 模式同时校验签名
+func (g *Guard) Authenticate(r *http.Request) (*model.IngestKey, []byte, error) {
+	key, bearerSecret, err := g.lookup(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxBytes := key.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = model.DefaultIngestMaxBytes
+	}
+	if r.ContentLength > int64(maxBytes) {
+		return nil, nil, errorf(http.StatusRequestEntityTooLarge, "payload exceeds %d bytes", maxBytes)
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, nil, errorf(http.StatusBadRequest, "read body: %v", err)
+	}
+	if len(body) > maxBytes {
+		return nil, nil, errorf(http.StatusRequestEntityTooLarge, "payload exceeds %d bytes", maxBytes)
+	}
+
+	if key.Mode == model.IngestHMAC {
+		ts, err := verifySignature(key, r.Header, body)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !g.remember(key.KeyID, r.Header.Get(HeaderSignature), ts) {
+			return nil, nil, errorf(http.StatusUnauthorized, "signature already used")
+		}
+	} else if subtle.ConstantTimeCompare([]byte(bearerSecret), []byte(key.Secret)) != 1 {
+		return nil, nil, errorf(http.StatusUnauthorized, "invalid ingestion key")
+	}
+	return key, body, nil
+}
+
+// lookup 从 X-ClawPanel-Key（HMAC）或 Authorization: Bearer <keyId>.<secret> 中找到密钥
+func (g *Guard) lookup(r *http.Request) (*model.IngestKey, string, error) {
+	keyID := r.Header.Get(HeaderKey)
+	secret := ""
+	mode := model.IngestHMAC
+	if keyID == "" {
+		token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if token == "" {
+			return nil, "", errorf(http.StatusUnauthorized, "ingestion key required")
+		}
+		var ok bool
+		keyID, secret, ok = strings.Cut(token, ".")
+		if !ok {
+			return nil, "", errorf(http.StatusUnauthorized, "invalid ingestion key")
+		}
+		mode = model.IngestBearer
+	}
+
+	g.mu.Lock()
+	key, ok := g.keys[keyID]
+	g.mu.Unlock()
+	if !ok || !key.Enabled {
+		return nil, "", errorf(http.StatusUnauthorized, "invalid ingestion key")
+	}
+	if key.Mode != mode {
+		return nil, "", errorf(http.StatusUnauthorized, "key %s requires %s authentication", key.KeyID, key.Mode)
+	}
+	return &key, secret, nil
+}
+
+// verifySignature 校验时间戳和签名，返回请求的签名时间
+func verifySignature(key *model.IngestKey, header http.Header, body []byte) (time.Time, error) {
+	timestamp := header.Get(HeaderTimestamp)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, errorf(http.StatusUnauthorized, "%s required", HeaderTimestamp)
+	}
+	ts := time.Unix(sec, 0)
+	if d := time.Since(ts); d > signatureTolerance || d < -signatureTolerance {
+		return time.Time{}, errorf(http.StatusUnauthorized, "timestamp out of range")
+	}
+	signature := strings.TrimPrefix(header.Get(HeaderSignature), "sha256=")
+	expected := webhook.Sign(key.Secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return time.Time{}, errorf(http.StatusUnauthorized, "invalid signature")
+	}
+	return ts, nil
+}
+
+// remember 记录签名直到其时间戳超出允许偏差，签名已出现过时返回 false
+func (g *Guard) remember(keyID, signature string, ts time.Time) bool {
+	now := time.Now()
+	id := keyID + ":" + strings.TrimPrefix(signature, "sha256=")
+	g.seenMu.Lock()
+	defer g.seenMu.Unlock()
+	if now.Sub(g.seenPruned) >= time.Minute {
+		for k, exp := range g.seen {
+			if now.After(exp) {
+				delete(g.seen, k)
+			}
+		}
+		g.seenPruned = now
+	}
+	if exp, ok := g.seen[id]; ok && !now.After(exp) {
+		return false
+	}
+	g.seen[id] = ts.Add(signatureTolerance)
+	return true
+}
+
+// CheckTime 校验接入方给出的事件时间（毫秒），与服务器时间相差超过 signatureTolerance 时拒绝。
+// 0 表示使用服务器时间
+func CheckTime(ms int64) error {
+	if ms == 0 {
+		return nil
+	}
+	if d := time.Since(time.UnixMilli(ms)); d > signatureTolerance || d < -signatureTolerance {
+		return errorf(http.StatusBadRequest, "time must be within %s of server time", signatureTolerance)
+	}
+	return nil
+}
+
+// Permit 检查 source / type 是否在密钥的白名单内。保留来源需在 sources 中逐字列出
+func Permit(key *model.IngestKey, source, eventType string) error {
+	if reserved(source) && !contains(key.Sources, source) {
+		return errorf(http.StatusForbidden, "source %q is reserved and must be listed explicitly for this key", source)
+	}
+	if !webhook.Match(key.Sources, source) {
+		return errorf(http.StatusForbidden, "source %q not allowed for this key", source)
+	}
+	if !webhook.Match(key.Types, eventType) {
+		return errorf(http.StatusForbidden, "type %q not allowed for this key", eventType)
+	}
+	return nil
+}
+
+func reserved(source string) bool {
+	return contains(ReservedSources, source)
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow 从密钥的令牌桶中取 n 个事件的额度，不足时返回 429 错误和需要等待的时间
+func (g *Guard) Allow(key *model.IngestKey, n int) (time.Duration, error) {
+	limit := key.RateLimit
+	if limit <= 0 {
+		limit = model.DefaultIngestRateLimit
+	}
+	if n > limit {
+		return 0, errorf(http.StatusRequestEntityTooLarge, "batch of %d events exceeds rate limit of %d per minute", n, limit)
+	}
+
+	now := time.Now()
+	rate := float64(limit) / 60 // 每秒补充
+	g.mu.Lock()
+	b := g.buckets[key.KeyID]
+	if b == nil {
+		b = &bucket{tokens: float64(limit), updated: now}
+		g.buckets[key.KeyID] = b
+	}
+	b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+	if b.tokens < float64(n) {
+		wait := time.Duration((float64(n) - b.tokens) / rate * float64(time.Second))
+		g.mu.Unlock()
+		return wait, errorf(http.StatusTooManyRequests, "rate limit exceeded (%d events per minute)", limit)
+	}
+	b.tokens -= float64(n)
+	touch := now.Sub(b.touched) >= touchInterval
+	if touch {
+		b.touched = now
+	}
+	g.mu.Unlock()
+
+	if touch {
+		model.TouchIngestKey(g.db, key.ID, now.UnixMilli())
+	}
+	return 0, nil
+}
+
+// NewKeyID 生成公开的密钥标识
+func NewKeyID() string {
+	return "ck_" + randomHex(6)
+}
+
+// NewSecret 生成密钥
+func NewSecret() string {
+	return randomHex(24)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -33,12 +33,8 @@ func Auth(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := ParseToken(cfg.JWTSecret, tokenStr)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"ok": false, "error": "认证令牌无效或已过期"})
 			c.Abort()
 			return
@@ -49,6 +45,21 @@ func Auth(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
+// ParseToken 校验 JWT 并返回声明，过期或签名错误时返回错误
+func ParseToken(secret, tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
 // GenerateToken 生成 JWT Token
 func GenerateToken(secret string) (string, error) {
 	claims := &Claims{
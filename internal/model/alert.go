@@ -0,0 +1,116 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AlertRule QQ 消息告警规则，所有已填写的条件同时满足才算命中
+type AlertRule struct {
+	ID               int64    `json:"id"`
+	Name             string   `json:"name"`
+	Enabled          bool     `json:"enabled"`
+	MessageType      string   `json:"messageType"` // group / private，留空匹配全部
+	Keywords         []string `json:"keywords"`    // 消息包含任一关键词（不区分大小写）
+	Regex            string   `json:"regex"`       // 消息匹配正则
+	UserIDs          []string `json:"userIds"`     // 发送者 QQ 号在列表中
+	GroupIDs         []string `json:"groupIds"`    // 群号在列表中
+	WebhookURL       string   `json:"webhookUrl"`  // 命中后额外投递到该地址
+	WebhookSecret    string   `json:"-"`           // 投递到 WebhookURL 时的签名密钥，不返回
+	HasWebhookSecret bool     `json:"hasWebhookSecret"`
+	CreatedAt        int64    `json:"createdAt"`
+	UpdatedAt        int64    `json:"updatedAt"`
+}
+
+const alertRuleColumns = "id, name, enabled, message_type, keywords, regex, user_ids, group_ids, webhook_url, webhook_secret, created_at, updated_at"
+
+// GetAlertRules 获取全部告警规则
+func GetAlertRules(db *sql.DB) ([]AlertRule, error) {
+	rows, err := db.Query("SELECT " + alertRuleColumns + " FROM alert_rules ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []AlertRule{}
+	for rows.Next() {
+		r, err := scanAlertRule(rows)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, *r)
+	}
+	return rules, nil
+}
+
+// GetAlertRule 按 ID 获取告警规则
+func GetAlertRule(db *sql.DB, id int64) (*AlertRule, error) {
+	return scanAlertRule(db.QueryRow("SELECT "+alertRuleColumns+" FROM alert_rules WHERE id = ?", id))
+}
+
+// SaveAlertRule 新建（ID 为 0）或更新告警规则
+func SaveAlertRule(db *sql.DB, r *AlertRule) error {
+	now := time.Now().UnixMilli()
+	keywords, _ := json.Marshal(nonNil(r.Keywords))
+	userIDs, _ := json.Marshal(nonNil(r.UserIDs))
+	groupIDs, _ := json.Marshal(nonNil(r.GroupIDs))
+	r.UpdatedAt = now
+	if r.ID == 0 {
+		r.CreatedAt = now
+		result, err := db.Exec(
+			"INSERT INTO alert_rules (name, enabled, message_type, keywords, regex, user_ids, group_ids, webhook_url, webhook_secret, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			r.Name, r.Enabled, r.MessageType, string(keywords), r.Regex, string(userIDs), string(groupIDs), r.WebhookURL, r.WebhookSecret, r.CreatedAt, r.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		r.ID, _ = result.LastInsertId()
+		return nil
+	}
+	result, err := db.Exec(
+		"UPDATE alert_rules SET name = ?, enabled = ?, message_type = ?, keywords = ?, regex = ?, user_ids = ?, group_ids = ?, webhook_url = ?, webhook_secret = ?, updated_at = ? WHERE id = ?",
+		r.Name, r.Enabled, r.MessageType, string(keywords), r.Regex, string(userIDs), string(groupIDs), r.WebhookURL, r.WebhookSecret, r.UpdatedAt, r.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteAlertRule 删除告警规则
+func DeleteAlertRule(db *sql.DB, id int64) error {
+	result, err := db.Exec("DELETE FROM alert_rules WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func scanAlertRule(row rowScanner) (*AlertRule, error) {
+	var r AlertRule
+	var keywords, userIDs, groupIDs string
+	err := row.Scan(&r.ID, &r.Name, &r.Enabled, &r.MessageType, &keywords, &r.Regex, &userIDs, &groupIDs, &r.WebhookURL, &r.WebhookSecret, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(keywords), &r.Keywords)
+	json.Unmarshal([]byte(userIDs), &r.UserIDs)
+	json.Unmarshal([]byte(groupIDs), &r.GroupIDs)
+	r.Keywords, r.UserIDs, r.GroupIDs = nonNil(r.Keywords), nonNil(r.UserIDs), nonNil(r.GroupIDs)
+	r.HasWebhookSecret = r.WebhookSecret != ""
+	return &r, nil
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -49,16 +49,182 @@ func migrate(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_events_time ON events(time DESC);
 	CREATE INDEX IF NOT EXISTS idx_events_source ON events(source);
 
+	CREATE TABLE IF NOT EXISTS qq_requests (
+		flag TEXT PRIMARY KEY,
+		type TEXT NOT NULL,
+		sub_type TEXT DEFAULT '',
+		self_id TEXT DEFAULT '',
+		instance TEXT NOT NULL DEFAULT '',
+		user_id TEXT NOT NULL,
+		group_id TEXT DEFAULT '',
+		comment TEXT DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'pending',
+		reason TEXT DEFAULT '',
+		time INTEGER NOT NULL,
+		handled_at INTEGER DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_qq_requests_status ON qq_requests(status, time DESC);
+
+	CREATE TABLE IF NOT EXISTS messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message_id TEXT NOT NULL,
+		self_id TEXT NOT NULL DEFAULT '',
+		message_type TEXT NOT NULL,
+		peer_id TEXT NOT NULL,
+		group_id TEXT DEFAULT '',
+		user_id TEXT NOT NULL,
+		sender_card TEXT DEFAULT '',
+		sender_nickname TEXT DEFAULT '',
+		segments TEXT NOT NULL DEFAULT '[]',
+		text TEXT DEFAULT '',
+		time INTEGER NOT NULL,
+		UNIQUE(self_id, message_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_messages_peer ON messages(message_type, peer_id, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_messages_conv ON messages(message_type, peer_id, self_id, id);
+	CREATE INDEX IF NOT EXISTS idx_messages_time ON messages(time);
+
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		enabled INTEGER NOT NULL DEFAULT 1,
+		message_type TEXT DEFAULT '',
+		keywords TEXT NOT NULL DEFAULT '[]',
+		regex TEXT DEFAULT '',
+		user_ids TEXT NOT NULL DEFAULT '[]',
+		group_ids TEXT NOT NULL DEFAULT '[]',
+		webhook_url TEXT DEFAULT '',
+		webhook_secret TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL DEFAULT '',
+		events TEXT NOT NULL DEFAULT '[]',
+		enabled INTEGER NOT NULL DEFAULT 1,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		event_id INTEGER NOT NULL DEFAULT 0,
+		event_type TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at INTEGER NOT NULL DEFAULT 0,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		delivered_at INTEGER NOT NULL DEFAULT 0,
+		target_url TEXT NOT NULL DEFAULT '',
+		target_secret TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+	CREATE INDEX IF NOT EXISTS idx_deliveries_webhook ON webhook_deliveries(webhook_id, id);
+
+	CREATE TABLE IF NOT EXISTS ingest_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		key_id TEXT NOT NULL UNIQUE,
+		secret TEXT NOT NULL,
+		mode TEXT NOT NULL DEFAULT 'bearer',
+		sources TEXT NOT NULL DEFAULT '[]',
+		types TEXT NOT NULL DEFAULT '[]',
+		rate_limit INTEGER NOT NULL DEFAULT 600,
+		max_bytes INTEGER NOT NULL DEFAULT 262144,
+		enabled INTEGER NOT NULL DEFAULT 1,
+		last_used_at INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS stats_hourly (
+		bucket INTEGER NOT NULL,
+		channel TEXT NOT NULL,
+		self_id TEXT NOT NULL DEFAULT '',
+		message_type TEXT NOT NULL DEFAULT '',
+		peer_id TEXT NOT NULL DEFAULT '',
+		received INTEGER NOT NULL DEFAULT 0,
+		sent INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (bucket, channel, self_id, message_type, peer_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS stats_users_daily (
+		day INTEGER NOT NULL,
+		channel TEXT NOT NULL,
+		self_id TEXT NOT NULL DEFAULT '',
+		group_id TEXT NOT NULL DEFAULT '',
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		count INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (day, channel, self_id, group_id, user_id)
+	);
+
 	CREATE TABLE IF NOT EXISTS settings (
 		key TEXT PRIMARY KEY,
 		value TEXT NOT NULL,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 	`
-	_, err := db.Exec(schema)
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	// 旧版本数据库补充新增列
+	if err := addColumn(db, "events", "severity", "TEXT NOT NULL DEFAULT 'info'"); err != nil {
+		return err
+	}
+	if err := addColumn(db, "qq_requests", "instance", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumn(db, "alert_rules", "webhook_secret", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumn(db, "webhook_deliveries", "target_url", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := addColumn(db, "webhook_deliveries", "target_secret", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return migrateEventsFTS(db)
+}
+
+// addColumn 列不存在时执行 ALTER TABLE ADD COLUMN
+func addColumn(db *sql.DB, table, column, def string) error {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	rows.Close()
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
 	return err
 }
 
+// 事件级别
+const (
+	SeverityInfo = "info"
+	SeverityHigh = "high"
+)
+
 // Event 事件日志
 type Event struct {
 	ID        int64  `json:"id"`
@@ -67,6 +233,8 @@ type Event struct {
 	Type     string `json:"type"`
 	Summary  string `json:"summary"`
 	Detail   string `json:"detail"`
+	Severity string `json:"severity"`
+	Snippet  string `json:"snippet,omitempty"` // 全文检索时的高亮摘录（已 HTML 转义）
 }
 
 // AddEvent 添加事件
@@ -74,9 +242,12 @@ func AddEvent(db *sql.DB, e *Event) (int64, error) {
 	if e.Time == 0 {
 		e.Time = time.Now().UnixMilli()
 	}
+	if e.Severity == "" {
+		e.Severity = SeverityInfo
+	}
 	result, err := db.Exec(
-		"INSERT INTO events (time, source, type, summary, detail) VALUES (?, ?, ?, ?, ?)",
-		e.Time, e.Source, e.Type, e.Summary, e.Detail,
+		"INSERT INTO events (time, source, type, summary, detail, severity) VALUES (?, ?, ?, ?, ?, ?)",
+		e.Time, e.Source, e.Type, e.Summary, e.Detail, e.Severity,
 	)
 	if err != nil {
 		return 0, err
@@ -84,50 +255,41 @@ func AddEvent(db *sql.DB, e *Event) (int64, error) {
 	return result.LastInsertId()
 }
 
-// GetEvents 获取事件列表
-func GetEvents(db *sql.DB, limit, offset int, source, search string) ([]Event, int, error) {
-	// 构建查询
-	where := "1=1"
-	args := []interface{}{}
-	if source != "" {
-		where += " AND source = ?"
-		args = append(args, source)
-	}
-	if search != "" {
-		where += " AND (summary LIKE ? OR detail LIKE ?)"
-		args = append(args, "%"+search+"%", "%"+search+"%")
-	}
-
-	// 总数
-	var total int
-	countArgs := make([]interface{}, len(args))
-	copy(countArgs, args)
-	err := db.QueryRow("SELECT COUNT(*) FROM events WHERE "+where, countArgs...).Scan(&total)
+// AddEvents 在一个事务中写入多条事件，写入后回填各事件的 ID
+func AddEvents(db *sql.DB, events []*Event) error {
+	tx, err := db.Begin()
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
+	defer tx.Rollback()
 
-	// 查询
-	query := fmt.Sprintf("SELECT id, time, source, type, summary, detail FROM events WHERE %s ORDER BY time DESC LIMIT ? OFFSET ?", where)
-	args = append(args, limit, offset)
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer rows.Close()
-
-	var events []Event
-	for rows.Next() {
-		var e Event
-		if err := rows.Scan(&e.ID, &e.Time, &e.Source, &e.Type, &e.Summary, &e.Detail); err != nil {
-			continue
+	now := time.Now().UnixMilli()
+	for _, e := range events {
+		if e.Time == 0 {
+			e.Time = now
 		}
-		events = append(events, e)
+		if e.Severity == "" {
+			e.Severity = SeverityInfo
+		}
+		result, err := tx.Exec(
+			"INSERT INTO events (time, source, type, summary, detail, severity) VALUES (?, ?, ?, ?, ?, ?)",
+			e.Time, e.Source, e.Type, e.Summary, e.Detail, e.Severity,
+		)
+		if err != nil {
+			return err
+		}
+		e.ID, _ = result.LastInsertId()
 	}
-	if events == nil {
-		events = []Event{}
+	return tx.Commit()
+}
+
+// GetEvents 获取事件列表
+func GetEvents(db *sql.DB, limit, offset int, source, search string) ([]Event, int, error) {
+	q := EventQuery{Limit: limit, Offset: offset, Search: search}
+	if source != "" {
+		q.Sources = []string{source}
 	}
-	return events, total, nil
+	return QueryEvents(db, q)
 }
 
 // ClearEvents 清空事件
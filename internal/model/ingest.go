@@ -0,0 +1,124 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// 接入密钥认证方式
+const (
+	IngestBearer = "bearer" // Authorization: Bearer <keyId>.<secret>
+	IngestHMAC   = "hmac"   // X-ClawPanel-Key + 时间戳 + HMAC-SHA256 签名，密钥不随请求传输
+)
+
+// 接入密钥默认限制
+const (
+	DefaultIngestRateLimit = 600       // 每分钟事件数
+	DefaultIngestMaxBytes  = 256 << 10 // 单个请求体字节数
+)
+
+// IngestKey 外部服务推送活动日志（/api/events/log）使用的接入密钥
+type IngestKey struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	KeyID      string   `json:"keyId"` // 公开标识，用于查找密钥
+	Secret     string   `json:"-"`
+	Mode       string   `json:"mode"`
+	Sources    []string `json:"sources"`   // 允许的 source 通配符，为空不限制
+	Types      []string `json:"types"`     // 允许的 type 通配符，为空不限制
+	RateLimit  int      `json:"rateLimit"` // 每分钟最多事件数
+	MaxBytes   int      `json:"maxBytes"`  // 请求体上限
+	Enabled    bool     `json:"enabled"`
+	LastUsedAt int64    `json:"lastUsedAt,omitempty"`
+	CreatedAt  int64    `json:"createdAt"`
+	UpdatedAt  int64    `json:"updatedAt"`
+}
+
+const ingestKeyColumns = "id, name, key_id, secret, mode, sources, types, rate_limit, max_bytes, enabled, last_used_at, created_at, updated_at"
+
+// GetIngestKeys 获取全部接入密钥
+func GetIngestKeys(db *sql.DB) ([]IngestKey, error) {
+	rows, err := db.Query("SELECT " + ingestKeyColumns + " FROM ingest_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []IngestKey{}
+	for rows.Next() {
+		k, err := scanIngestKey(rows)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, *k)
+	}
+	return keys, nil
+}
+
+// GetIngestKey 按 ID 获取接入密钥
+func GetIngestKey(db *sql.DB, id int64) (*IngestKey, error) {
+	return scanIngestKey(db.QueryRow("SELECT "+ingestKeyColumns+" FROM ingest_keys WHERE id = ?", id))
+}
+
+// SaveIngestKey 新建（ID 为 0）或更新接入密钥
+func SaveIngestKey(db *sql.DB, k *IngestKey) error {
+	now := time.Now().UnixMilli()
+	sources, _ := json.Marshal(nonNil(k.Sources))
+	types, _ := json.Marshal(nonNil(k.Types))
+	k.UpdatedAt = now
+	if k.ID == 0 {
+		k.CreatedAt = now
+		result, err := db.Exec(
+			"INSERT INTO ingest_keys (name, key_id, secret, mode, sources, types, rate_limit, max_bytes, enabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			k.Name, k.KeyID, k.Secret, k.Mode, string(sources), string(types), k.RateLimit, k.MaxBytes, k.Enabled, k.CreatedAt, k.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		k.ID, _ = result.LastInsertId()
+		return nil
+	}
+	result, err := db.Exec(
+		"UPDATE ingest_keys SET name = ?, secret = ?, mode = ?, sources = ?, types = ?, rate_limit = ?, max_bytes = ?, enabled = ?, updated_at = ? WHERE id = ?",
+		k.Name, k.Secret, k.Mode, string(sources), string(types), k.RateLimit, k.MaxBytes, k.Enabled, k.UpdatedAt, k.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteIngestKey 删除接入密钥
+func DeleteIngestKey(db *sql.DB, id int64) error {
+	result, err := db.Exec("DELETE FROM ingest_keys WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TouchIngestKey 记录密钥最近使用时间
+func TouchIngestKey(db *sql.DB, id, t int64) error {
+	_, err := db.Exec("UPDATE ingest_keys SET last_used_at = ? WHERE id = ?", t, id)
+	return err
+}
+
+func scanIngestKey(row rowScanner) (*IngestKey, error) {
+	var k IngestKey
+	var sources, types string
+	if err := row.Scan(&k.ID, &k.Name, &k.KeyID, &k.Secret, &k.Mode, &sources, &types, &k.RateLimit, &k.MaxBytes, &k.Enabled, &k.LastUsedAt, &k.CreatedAt, &k.UpdatedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(sources), &k.Sources)
+	json.Unmarshal([]byte(types), &k.Types)
+	k.Sources = nonNil(k.Sources)
+	k.Types = nonNil(k.Types)
+	return &k, nil
+}
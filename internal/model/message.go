@@ -0,0 +1,153 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Message OneBot11 消息事件，按 (self_id, message_id) 去重
+type Message struct {
+	ID          int64           `json:"id"` // 自增序号，用作分页游标
+	MessageID   string          `json:"messageId"`
+	SelfID      string          `json:"selfId"`
+	MessageType string          `json:"messageType"` // group, private
+	PeerID      string          `json:"peerId"`      // 会话对象：群号或好友 QQ 号
+	GroupID     string          `json:"groupId"`
+	UserID      string          `json:"userId"`
+	SenderCard  string          `json:"senderCard"`
+	SenderNick  string          `json:"senderNickname"`
+	Segments    json.RawMessage `json:"segments"` // 原始消息段 JSON
+	Text        string          `json:"text"`     // 纯文本预览
+	Time        int64           `json:"time"`
+}
+
+// Conversation 会话概要
+type Conversation struct {
+	MessageType string `json:"messageType"`
+	PeerID      string `json:"peerId"`
+	SelfID      string `json:"selfId"`
+	Count       int    `json:"count"`
+	LastTime    int64  `json:"lastTime"`
+	LastText    string `json:"lastText"`
+}
+
+const messageColumns = "id, message_id, self_id, message_type, peer_id, group_id, user_id, sender_card, sender_nickname, segments, text, time"
+
+// SaveMessage 保存消息，同一账号下 message_id 重复时忽略
+func SaveMessage(db *sql.DB, m *Message) error {
+	if m.Time == 0 {
+		m.Time = time.Now().UnixMilli()
+	}
+	if len(m.Segments) == 0 {
+		m.Segments = json.RawMessage("[]")
+	}
+	result, err := db.Exec(
+		`INSERT OR IGNORE INTO messages (message_id, self_id, message_type, peer_id, group_id, user_id, sender_card, sender_nickname, segments, text, time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.MessageID, m.SelfID, m.MessageType, m.PeerID, m.GroupID, m.UserID, m.SenderCard, m.SenderNick, string(m.Segments), m.Text, m.Time,
+	)
+	if err != nil {
+		return err
+	}
+	m.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetMessage 按账号和 message_id 获取消息，selfID 为空时匹配任意账号
+func GetMessage(db *sql.DB, selfID, messageID string) (*Message, error) {
+	query := "SELECT " + messageColumns + " FROM messages WHERE message_id = ?"
+	args := []interface{}{messageID}
+	if selfID != "" {
+		query += " AND self_id = ?"
+		args = append(args, selfID)
+	}
+	query += " ORDER BY id DESC LIMIT 1"
+	return scanMessage(db.QueryRow(query, args...))
+}
+
+// GetConversation 按会话倒序获取消息，before 为上一页最后一条的 id（0 表示从最新开始）
+func GetConversation(db *sql.DB, messageType, peerID, selfID string, before int64, limit int) ([]Message, error) {
+	query := "SELECT " + messageColumns + " FROM messages WHERE message_type = ? AND peer_id = ?"
+	args := []interface{}{messageType, peerID}
+	if selfID != "" {
+		query += " AND self_id = ?"
+		args = append(args, selfID)
+	}
+	if before > 0 {
+		query += " AND id < ?"
+		args = append(args, before)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, *m)
+	}
+	return messages, nil
+}
+
+// GetConversations 列出会话，按最后一条消息时间倒序，messageType 为空时返回全部
+func GetConversations(db *sql.DB, messageType, selfID string, limit int) ([]Conversation, error) {
+	where := "1=1"
+	args := []interface{}{}
+	if messageType != "" {
+		where += " AND message_type = ?"
+		args = append(args, messageType)
+	}
+	if selfID != "" {
+		where += " AND self_id = ?"
+		args = append(args, selfID)
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(`
+		SELECT m.message_type, m.peer_id, m.self_id, c.cnt, m.time, m.text
+		FROM (
+			SELECT MAX(id) AS last_id, COUNT(*) AS cnt FROM messages
+			WHERE `+where+`
+			GROUP BY message_type, peer_id, self_id
+		) c JOIN messages m ON m.id = c.last_id
+		ORDER BY m.id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Conversation{}
+	for rows.Next() {
+		var cv Conversation
+		if err := rows.Scan(&cv.MessageType, &cv.PeerID, &cv.SelfID, &cv.Count, &cv.LastTime, &cv.LastText); err != nil {
+			continue
+		}
+		list = append(list, cv)
+	}
+	return list, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMessage(row rowScanner) (*Message, error) {
+	var m Message
+	var segments string
+	err := row.Scan(&m.ID, &m.MessageID, &m.SelfID, &m.MessageType, &m.PeerID, &m.GroupID, &m.UserID,
+		&m.SenderCard, &m.SenderNick, &segments, &m.Text, &m.Time)
+	if err != nil {
+		return nil, err
+	}
+	m.Segments = json.RawMessage(segments)
+	return &m, nil
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+// 请求处理状态
+const (
+	RequestPending  = "pending"
+	RequestApproved = "approved"
+	RequestRejected = "rejected"
+)
+
+// Request QQ 好友 / 入群请求
+type Request struct {
+	Flag      string `json:"flag"`
+	Type      string `json:"type"`    // friend, group
+	SubType   string `json:"subType"` // add, invite (仅 group)
+	SelfID    string `json:"selfId"`
+	Instance  string `json:"instance"` // 收到请求的 NapCat 实例 ID，审批时交给该实例处理
+	UserID    string `json:"userId"`
+	GroupID   string `json:"groupId"`
+	Comment   string `json:"comment"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+	Time      int64  `json:"time"`
+	HandledAt int64  `json:"handledAt"`
+}
+
+const requestColumns = "flag, type, sub_type, self_id, instance, user_id, group_id, comment, status, reason, time, handled_at"
+
+// SaveRequest 保存请求，flag 重复时忽略
+func SaveRequest(db *sql.DB, r *Request) error {
+	if r.Time == 0 {
+		r.Time = time.Now().UnixMilli()
+	}
+	if r.Status == "" {
+		r.Status = RequestPending
+	}
+	_, err := db.Exec(
+		"INSERT OR IGNORE INTO qq_requests ("+requestColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		r.Flag, r.Type, r.SubType, r.SelfID, r.Instance, r.UserID, r.GroupID, r.Comment, r.Status, r.Reason, r.Time, r.HandledAt,
+	)
+	return err
+}
+
+// GetRequest 按 flag 获取请求
+func GetRequest(db *sql.DB, flag string) (*Request, error) {
+	var r Request
+	err := db.QueryRow("SELECT "+requestColumns+" FROM qq_requests WHERE flag = ?", flag).Scan(
+		&r.Flag, &r.Type, &r.SubType, &r.SelfID, &r.Instance, &r.UserID, &r.GroupID, &r.Comment, &r.Status, &r.Reason, &r.Time, &r.HandledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetRequests 获取请求列表，status 为空时返回全部
+func GetRequests(db *sql.DB, status string, limit int) ([]Request, error) {
+	query := "SELECT " + requestColumns + " FROM qq_requests"
+	args := []interface{}{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY time DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []Request{}
+	for rows.Next() {
+		var r Request
+		if err := rows.Scan(&r.Flag, &r.Type, &r.SubType, &r.SelfID, &r.Instance, &r.UserID, &r.GroupID, &r.Comment, &r.Status, &r.Reason, &r.Time, &r.HandledAt); err != nil {
+			continue
+		}
+		requests = append(requests, r)
+	}
+	return requests, nil
+}
+
+// SetRequestStatus 更新请求处理结果
+func SetRequestStatus(db *sql.DB, flag, status, reason string) error {
+	_, err := db.Exec(
+		"UPDATE qq_requests SET status = ?, reason = ?, handled_at = ? WHERE flag = ?",
+		status, reason, time.Now().UnixMilli(), flag,
+	)
+	return err
+}
@@ -0,0 +1,294 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 保留策略相关的 settings 键
+const (
+	retentionKey        = "events.retention"
+	retentionLastRunKey = "events.retention.lastRun"
+	lastVacuumKey       = "events.retention.lastVacuum"
+)
+
+// pruneBatch 每次删除的行数，分批删除避免长时间占用写锁
+const pruneBatch = 5000
+
+// RetentionLimit 保留上限，0 表示不限制
+type RetentionLimit struct {
+	MaxAgeDays int `json:"maxAgeDays"`
+	MaxRows    int `json:"maxRows"`
+}
+
+// RetentionPolicy 活动日志保留策略。未单独配置的来源共用默认上限，
+// Sources 中的来源（如 system、qq）使用各自的上限；消息记录与用户发言统计单独设置上限
+type RetentionPolicy struct {
+	Enabled         bool                      `json:"enabled"`
+	IntervalMinutes int                       `json:"intervalMinutes"` // 自动清理周期
+	Default         RetentionLimit            `json:"default"`
+	Sources         map[string]RetentionLimit `json:"sources"`
+	Messages        RetentionLimit            `json:"messages"`      // messages 表
+	UserStatsDays   int                       `json:"userStatsDays"` // stats_users_daily 保留天数
+	// VacuumFreeRatio 空闲页占比超过该值时清理后执行 VACUUM，0 表示不自动 VACUUM
+	VacuumFreeRatio float64 `json:"vacuumFreeRatio"`
+}
+
+// DefaultRetentionPolicy 默认策略：保留 30 天 / 20 万条，系统事件保留 90 天，
+// 消息记录保留 90 天 / 50 万条，用户发言统计保留 365 天。
+// 默认不启用自动清理，避免升级后未经确认删除历史记录
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		Enabled:         false,
+		IntervalMinutes: 60,
+		Default:         RetentionLimit{MaxAgeDays: 30, MaxRows: 200000},
+		Sources: map[string]RetentionLimit{
+			"system": {MaxAgeDays: 90, MaxRows: 50000},
+		},
+		Messages:        RetentionLimit{MaxAgeDays: 90, MaxRows: 500000},
+		UserStatsDays:   365,
+		VacuumFreeRatio: 0.3,
+	}
+}
+
+// PruneResult 一次清理的结果
+type PruneResult struct {
+	Time       int64            `json:"time"`
+	Deleted    int64            `json:"deleted"`
+	BySource   map[string]int64 `json:"bySource,omitempty"` // 仅单独配置的来源，其余计入 "*"
+	Deliveries int64            `json:"deliveries"`         // 清理的 Webhook 投递记录
+	Messages   int64            `json:"messages"`           // 清理的消息记录
+	UserStats  int64            `json:"userStats"`          // 清理的用户发言日统计
+	Vacuumed   bool             `json:"vacuumed"`
+	DurationMs int64            `json:"durationMs"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// EventStorage 活动日志与数据库的存储占用
+type EventStorage struct {
+	Rows       int64            `json:"rows"`
+	BySource   map[string]int64 `json:"bySource"`
+	OldestTime int64            `json:"oldestTime,omitempty"`
+	TableBytes int64            `json:"tableBytes"` // events 表及其索引
+	DBBytes    int64            `json:"dbBytes"`    // 整个数据库文件（page_count × page_size）
+	FreeBytes  int64            `json:"freeBytes"`  // 空闲页，VACUUM 可回收
+	LastPrune  *PruneResult     `json:"lastPrune,omitempty"`
+	LastVacuum int64            `json:"lastVacuum,omitempty"`
+}
+
+// GetRetentionPolicy 读取保留策略，未配置时返回默认策略
+func GetRetentionPolicy(db *sql.DB) RetentionPolicy {
+	policy := DefaultRetentionPolicy()
+	if value, err := GetSetting(db, retentionKey); err == nil && value != "" {
+		var saved RetentionPolicy
+		if json.Unmarshal([]byte(value), &saved) == nil {
+			policy = saved
+		}
+	}
+	if policy.Sources == nil {
+		policy.Sources = map[string]RetentionLimit{}
+	}
+	if policy.IntervalMinutes <= 0 {
+		policy.IntervalMinutes = 60
+	}
+	return policy
+}
+
+// SaveRetentionPolicy 保存保留策略
+func SaveRetentionPolicy(db *sql.DB, p RetentionPolicy) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return SetSetting(db, retentionKey, string(data))
+}
+
+// GetLastPrune 读取最近一次清理结果
+func GetLastPrune(db *sql.DB) *PruneResult {
+	value, err := GetSetting(db, retentionLastRunKey)
+	if err != nil || value == "" {
+		return nil
+	}
+	var r PruneResult
+	if json.Unmarshal([]byte(value), &r) != nil {
+		return nil
+	}
+	return &r
+}
+
+// PruneEvents 按保留策略删除过期事件、消息记录与用户发言统计，并清理超过默认保留天数的已完成 Webhook 投递记录。
+// 结果写入 settings，vacuum 为 true 时无论空闲页比例都执行 VACUUM
+func PruneEvents(db *sql.DB, p RetentionPolicy, vacuum bool) PruneResult {
+	start := time.Now()
+	result := PruneResult{Time: start.UnixMilli(), BySource: map[string]int64{}}
+	err := func() error {
+		// 单独配置的来源
+		others := []interface{}{}
+		for source, limit := range p.Sources {
+			n, err := pruneWhere(db, "events", "source = ?", []interface{}{source}, limit, start)
+			if err != nil {
+				return err
+			}
+			result.BySource[source] = n
+			result.Deleted += n
+			others = append(others, source)
+		}
+		// 其余来源使用默认上限
+		where, args := "1=1", []interface{}{}
+		if len(others) > 0 {
+			where = "source NOT IN (?" + strings.Repeat(", ?", len(others)-1) + ")"
+			args = others
+		}
+		n, err := pruneWhere(db, "events", where, args, p.Default, start)
+		if err != nil {
+			return err
+		}
+		result.BySource["*"] = n
+		result.Deleted += n
+
+		if result.Messages, err = pruneWhere(db, "messages", "1=1", nil, p.Messages, start); err != nil {
+			return err
+		}
+		if p.UserStatsDays > 0 {
+			cutoff := DayStart(start.AddDate(0, 0, -p.UserStatsDays).UnixMilli())
+			if result.UserStats, err = deleteBatches(db, "stats_users_daily", "day < ?", []interface{}{cutoff}); err != nil {
+				return err
+			}
+		}
+
+		if p.Default.MaxAgeDays > 0 {
+			cutoff := start.AddDate(0, 0, -p.Default.MaxAgeDays).UnixMilli()
+			res, err := db.Exec("DELETE FROM webhook_deliveries WHERE status != ? AND created_at < ?", DeliveryPending, cutoff)
+			if err != nil {
+				return err
+			}
+			result.Deliveries, _ = res.RowsAffected()
+		}
+
+		db.Exec("PRAGMA optimize")
+		pruned := result.Deleted + result.Messages + result.UserStats
+		if vacuum || (p.VacuumFreeRatio > 0 && pruned > 0 && freeRatio(db) >= p.VacuumFreeRatio) {
+			if err := Vacuum(db); err != nil {
+				return err
+			}
+			result.Vacuumed = true
+		}
+		return nil
+	}()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	if data, err := json.Marshal(result); err == nil {
+		SetSetting(db, retentionLastRunKey, string(data))
+	}
+	return result
+}
+
+// pruneWhere 删除 table（events 或 messages）中满足 where 条件且超出 limit 的记录，返回删除行数
+func pruneWhere(db *sql.DB, table, where string, args []interface{}, limit RetentionLimit, now time.Time) (int64, error) {
+	var deleted int64
+	if limit.MaxAgeDays > 0 {
+		cutoff := now.AddDate(0, 0, -limit.MaxAgeDays).UnixMilli()
+		n, err := deleteBatches(db, table, where+" AND time < ?", append(append([]interface{}{}, args...), cutoff))
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	if limit.MaxRows > 0 {
+		// 第 MaxRows+1 新的记录及更早的记录删除
+		var cutoffID int64
+		err := db.QueryRow(
+			fmt.Sprintf("SELECT id FROM %s WHERE %s ORDER BY id DESC LIMIT 1 OFFSET ?", table, where),
+			append(append([]interface{}{}, args...), limit.MaxRows)...,
+		).Scan(&cutoffID)
+		if err != nil && err != sql.ErrNoRows {
+			return deleted, err
+		}
+		if cutoffID > 0 {
+			n, err := deleteBatches(db, table, where+" AND id <= ?", append(append([]interface{}{}, args...), cutoffID))
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+	return deleted, nil
+}
+
+func deleteBatches(db *sql.DB, table, where string, args []interface{}) (int64, error) {
+	var total int64
+	query := fmt.Sprintf("DELETE FROM %s WHERE rowid IN (SELECT rowid FROM %s WHERE %s LIMIT %d)", table, table, where, pruneBatch)
+	for {
+		res, err := db.Exec(query, args...)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if n < pruneBatch {
+			return total, nil
+		}
+	}
+}
+
+// Vacuum 重建数据库文件回收空闲页，并截断 WAL
+func Vacuum(db *sql.DB) error {
+	if _, err := db.Exec("VACUUM"); err != nil {
+		return err
+	}
+	db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	return SetSetting(db, lastVacuumKey, fmt.Sprint(time.Now().UnixMilli()))
+}
+
+func pragmaInt(db *sql.DB, name string) int64 {
+	var n int64
+	db.QueryRow("PRAGMA " + name).Scan(&n)
+	return n
+}
+
+func freeRatio(db *sql.DB) float64 {
+	pages := pragmaInt(db, "page_count")
+	if pages == 0 {
+		return 0
+	}
+	return float64(pragmaInt(db, "freelist_count")) / float64(pages)
+}
+
+// GetEventStorage 统计活动日志行数与数据库占用
+func GetEventStorage(db *sql.DB) (*EventStorage, error) {
+	st := &EventStorage{BySource: map[string]int64{}}
+	rows, err := db.Query("SELECT source, COUNT(*) FROM events GROUP BY source")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var source string
+		var n int64
+		if rows.Scan(&source, &n) == nil {
+			st.BySource[source] = n
+			st.Rows += n
+		}
+	}
+	rows.Close()
+
+	var oldest sql.NullInt64
+	db.QueryRow("SELECT MIN(time) FROM events").Scan(&oldest)
+	st.OldestTime = oldest.Int64
+
+	// dbstat 统计表及其索引实际占用的页
+	db.QueryRow("SELECT COALESCE(SUM(pgsize), 0) FROM dbstat WHERE name IN (SELECT name FROM sqlite_master WHERE tbl_name = 'events')").Scan(&st.TableBytes)
+	pageSize := pragmaInt(db, "page_size")
+	st.DBBytes = pragmaInt(db, "page_count") * pageSize
+	st.FreeBytes = pragmaInt(db, "freelist_count") * pageSize
+
+	st.LastPrune = GetLastPrune(db)
+	if v, err := GetSetting(db, lastVacuumKey); err == nil {
+		fmt.Sscan(v, &st.LastVacuum)
+	}
+	return st, nil
+}
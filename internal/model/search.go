@@ -0,0 +1,412 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ftsMinTerm trigram 分词下可走全文索引的最短关键词（字符数），更短的关键词回退到 LIKE
+const ftsMinTerm = 3
+
+// snippetRadius 摘录片段在命中位置前后保留的字符数
+const snippetRadius = 40
+
+// EventQuery 活动日志查询条件
+type EventQuery struct {
+	Limit      int
+	Offset     int
+	Sources    []string // 多个来源任一匹配
+	TypePrefix string   // 类型前缀，如 request. 或 notice.group_
+	Severity   string
+	Since      int64 // 毫秒时间戳，含
+	Until      int64 // 毫秒时间戳，不含
+	// Search 全文检索：空格分隔的关键词需全部命中，"双引号" 内为短语
+	Search string
+	// SortByTime 全文检索时按时间而非相关度排序
+	SortByTime bool
+	// Ascending 按时间正序
+	Ascending bool
+
+	// 游标分页，按 (time, id) 定位，设置后忽略 Offset 并按时间排序。
+	// Before 向更早翻页（倒序），Later 向更新翻页（正序，用于导出）
+	Before *EventCursor
+	Later  *EventCursor
+	// After 轮询新写入的事件：只按游标中的 id 过滤，返回 id 更大的事件并按 id 正序。
+	// id 为自增主键，按写入顺序递增，时间戳较早的导入 / 推送事件也不会遗漏
+	After *EventCursor
+	// SkipCount 不统计总数，返回的 total 为 -1
+	SkipCount bool
+}
+
+// EventCursor 事件分页游标
+type EventCursor struct {
+	Time int64
+	ID   int64
+}
+
+// ErrInvalidCursor 游标格式错误
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorOf 事件对应的游标
+func CursorOf(e Event) *EventCursor {
+	return &EventCursor{Time: e.Time, ID: e.ID}
+}
+
+// String 编码为不透明字符串
+func (c *EventCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d.%d", c.Time, c.ID)))
+}
+
+// ParseEventCursor 解析 String 生成的游标
+func ParseEventCursor(s string) (*EventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	t, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	c := &EventCursor{}
+	if c.Time, err = strconv.ParseInt(t, 10, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// migrateEventsFTS 创建 events 的 FTS5 外部内容索引（trigram 分词，支持中文子串）及同步触发器，
+// 首次创建时为已有数据重建索引
+func migrateEventsFTS(db *sql.DB) error {
+	var exists int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'events_fts'").Scan(&exists)
+
+	_, err := db.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS events_fts USING fts5(
+		summary, detail,
+		content = 'events', content_rowid = 'id',
+		tokenize = 'trigram'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS events_fts_insert AFTER INSERT ON events BEGIN
+		INSERT INTO events_fts(rowid, summary, detail) VALUES (new.id, new.summary, new.detail);
+	END;
+	CREATE TRIGGER IF NOT EXISTS events_fts_delete AFTER DELETE ON events BEGIN
+		INSERT INTO events_fts(events_fts, rowid, summary, detail) VALUES ('delete', old.id, old.summary, old.detail);
+	END;
+	CREATE TRIGGER IF NOT EXISTS events_fts_update AFTER UPDATE ON events BEGIN
+		INSERT INTO events_fts(events_fts, rowid, summary, detail) VALUES ('delete', old.id, old.summary, old.detail);
+		INSERT INTO events_fts(rowid, summary, detail) VALUES (new.id, new.summary, new.detail);
+	END;
+	`)
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		_, err = db.Exec("INSERT INTO events_fts(events_fts) VALUES ('rebuild')")
+	}
+	return err
+}
+
+// querier *sql.DB 与 *sql.Tx 共有的查询方法
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// QueryEvents 按条件查询活动日志。有全文关键词时默认按相关度排序并为每条结果生成高亮摘录
+func QueryEvents(db *sql.DB, q EventQuery) ([]Event, int, error) {
+	return queryEvents(db, q)
+}
+
+// QueryEventsHead 同 QueryEvents，另返回查询时最大的事件 id，作为 After 轮询的起点。
+// 两次查询在同一读事务中执行，之后写入的事件 id 一定更大
+func QueryEventsHead(db *sql.DB, q EventQuery) ([]Event, int, int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer tx.Rollback()
+	var head sql.NullInt64
+	if err := tx.QueryRow("SELECT MAX(id) FROM events").Scan(&head); err != nil {
+		return nil, 0, 0, err
+	}
+	events, total, err := queryEvents(tx, q)
+	return events, total, head.Int64, err
+}
+
+func queryEvents(db querier, q EventQuery) ([]Event, int, error) {
+	from := "events e"
+	where := "1=1"
+	args := []interface{}{}
+
+	if len(q.Sources) == 1 {
+		where += " AND e.source = ?"
+		args = append(args, q.Sources[0])
+	} else if len(q.Sources) > 1 {
+		where += " AND e.source IN (?" + strings.Repeat(", ?", len(q.Sources)-1) + ")"
+		for _, s := range q.Sources {
+			args = append(args, s)
+		}
+	}
+	if q.TypePrefix != "" {
+		where += ` AND e.type LIKE ? ESCAPE '\'`
+		args = append(args, escapeLike(q.TypePrefix)+"%")
+	}
+	if q.Severity != "" {
+		where += " AND e.severity = ?"
+		args = append(args, q.Severity)
+	}
+	if q.Since > 0 {
+		where += " AND e.time >= ?"
+		args = append(args, q.Since)
+	}
+	if q.Until > 0 {
+		where += " AND e.time < ?"
+		args = append(args, q.Until)
+	}
+
+	terms := SearchTerms(q.Search)
+	order := "e.time DESC, e.id DESC"
+	if q.Ascending {
+		order = "e.time ASC, e.id ASC"
+	}
+	keyset := ""
+	var keysetArgs []interface{}
+	if q.After != nil {
+		keyset, order = " AND e.id > ?", "e.id ASC"
+		keysetArgs = []interface{}{q.After.ID}
+	} else if q.Later != nil {
+		keyset, order = " AND (e.time, e.id) > (?, ?)", "e.time ASC, e.id ASC"
+		keysetArgs = []interface{}{q.Later.Time, q.Later.ID}
+	} else if q.Before != nil {
+		keyset = " AND (e.time, e.id) < (?, ?)"
+		keysetArgs = []interface{}{q.Before.Time, q.Before.ID}
+	}
+	var ftsTerms []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) >= ftsMinTerm {
+			ftsTerms = append(ftsTerms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+			continue
+		}
+		where += ` AND (e.summary LIKE ? ESCAPE '\' OR e.detail LIKE ? ESCAPE '\')`
+		like := "%" + escapeLike(term) + "%"
+		args = append(args, like, like)
+	}
+	if len(ftsTerms) > 0 {
+		from = "events_fts JOIN events e ON e.id = events_fts.rowid"
+		where = "events_fts MATCH ? AND " + where
+		args = append([]interface{}{strings.Join(ftsTerms, " ")}, args...)
+		if !q.SortByTime && keyset == "" {
+			// 摘要命中的权重高于详情
+			order = "bm25(events_fts, 2.0, 1.0), e.time DESC"
+		}
+	}
+
+	// 总数不受游标影响，表示满足筛选条件的全部事件数
+	total := -1
+	if !q.SkipCount {
+		if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", from, where), args...).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	offset := q.Offset
+	if keyset != "" {
+		offset = 0
+	}
+	query := fmt.Sprintf("SELECT e.id, e.time, e.source, e.type, e.summary, e.detail, e.severity FROM %s WHERE %s%s ORDER BY %s LIMIT ? OFFSET ?", from, where, keyset, order)
+	args = append(append(args, keysetArgs...), q.Limit, offset)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Time, &e.Source, &e.Type, &e.Summary, &e.Detail, &e.Severity); err != nil {
+			continue
+		}
+		if len(terms) > 0 {
+			e.Snippet = Highlight(e.Summary, terms)
+			if e.Snippet == "" {
+				e.Snippet = Highlight(e.Detail, terms)
+			}
+		}
+		events = append(events, e)
+	}
+	return events, total, nil
+}
+
+// SearchTerms 拆分检索词：按空白分隔，"双引号" 内作为一个短语
+func SearchTerms(s string) []string {
+	var terms []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			if end := strings.IndexByte(s[1:], '"'); end >= 0 {
+				if phrase := strings.TrimSpace(s[1 : end+1]); phrase != "" {
+					terms = append(terms, phrase)
+				}
+				s = s[end+2:]
+				continue
+			}
+			s = s[1:]
+			continue
+		}
+		end := strings.IndexFunc(s, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' || r == '　' })
+		if end < 0 {
+			end = len(s)
+		}
+		terms = append(terms, s[:end])
+		s = s[end:]
+	}
+	return terms
+}
+
+// Highlight 截取文本中首个命中关键词附近的片段，HTML 转义后用 <mark> 标记所有命中，未命中返回空。
+// 按字符逐个转小写比较，命中位置与原文字符一一对应（İ、ẞ、开尔文符号等转小写后字节长度会变）
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := foldRunes(text)
+	folded := make([][]rune, 0, len(terms))
+	for _, t := range terms {
+		if t != "" {
+			folded = append(folded, foldRunes(t))
+		}
+	}
+	first := -1
+	for _, t := range folded {
+		if i := indexRunes(lower, t); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	// 以命中位置为中心截取（按字符）
+	start, end := first-snippetRadius, first+snippetRadius*2
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(runes) {
+		end, suffix = len(runes), ""
+	}
+	window, windowLower := runes[start:end], lower[start:end]
+
+	// 标记窗口内所有命中区间
+	marks := make([]bool, len(window))
+	for _, t := range folded {
+		for off := 0; ; {
+			i := indexRunes(windowLower[off:], t)
+			if i < 0 {
+				break
+			}
+			for j := off + i; j < off+i+len(t); j++ {
+				marks[j] = true
+			}
+			off += i + len(t)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	open := false
+	for i, r := range window {
+		if marks[i] && !open {
+			b.WriteString("<mark>")
+			open = true
+		} else if !marks[i] && open {
+			b.WriteString("</mark>")
+			open = false
+		}
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	b.WriteString(suffix)
+	return b.String()
+}
+
+// foldRunes 逐字符转小写，结果与原文字符一一对应
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// indexRunes 返回 sub 在 s 中首次出现的字符下标，未找到返回 -1
+func indexRunes(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if slices.Equal(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ImportEvents 在一个事务中写入导入的事件。ID 未被占用时保留原 ID；
+// 已存在相同 ID 且时间、来源、类型、摘要一致的视为重复跳过，其余分配新 ID
+func ImportEvents(db *sql.DB, events []Event) (imported, skipped int, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	for _, e := range events {
+		if e.Severity == "" {
+			e.Severity = SeverityInfo
+		}
+		if e.ID > 0 {
+			var t int64
+			var source, typ, summary string
+			err := tx.QueryRow("SELECT time, source, type, summary FROM events WHERE id = ?", e.ID).Scan(&t, &source, &typ, &summary)
+			if err == nil && t == e.Time && source == e.Source && typ == e.Type && summary == e.Summary {
+				skipped++
+				continue
+			}
+			if err == sql.ErrNoRows {
+				if _, err := tx.Exec(
+					"INSERT INTO events (id, time, source, type, summary, detail, severity) VALUES (?, ?, ?, ?, ?, ?, ?)",
+					e.ID, e.Time, e.Source, e.Type, e.Summary, e.Detail, e.Severity,
+				); err != nil {
+					return 0, 0, err
+				}
+				imported++
+				continue
+			}
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO events (time, source, type, summary, detail, severity) VALUES (?, ?, ?, ?, ?, ?)",
+			e.Time, e.Source, e.Type, e.Summary, e.Detail, e.Severity,
+		); err != nil {
+			return 0, 0, err
+		}
+		imported++
+	}
+	return imported, skipped, tx.Commit()
+}
@@ -0,0 +1,419 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 汇总进度（已处理到的最大 id）保存在 settings 中
+const (
+	statsMessagesMark = "stats.rollup.messages"
+	statsEventsMark   = "stats.rollup.events"
+	rollupBatch       = 5000
+)
+
+// StatsFilter 统计查询条件，时间为毫秒时间戳
+type StatsFilter struct {
+	Since   int64
+	Until   int64
+	Channel string
+	SelfID  string
+	GroupID string
+}
+
+// StatsPoint 时间序列中的一个点
+type StatsPoint struct {
+	Time     int64 `json:"time"`
+	Received int64 `json:"received"`
+	Sent     int64 `json:"sent"`
+}
+
+// ChannelStats 按通道汇总
+type ChannelStats struct {
+	Channel  string `json:"channel"`
+	Received int64  `json:"received"`
+	Sent     int64  `json:"sent"`
+}
+
+// GroupStats 群活跃度
+type GroupStats struct {
+	GroupID  string `json:"groupId"`
+	SelfID   string `json:"selfId"`
+	Channel  string `json:"channel"`
+	Received int64  `json:"received"`
+	Sent     int64  `json:"sent"`
+	Total    int64  `json:"total"`
+}
+
+// UserStats 用户发言数
+type UserStats struct {
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	Channel string `json:"channel"`
+	Count   int64  `json:"count"`
+}
+
+// hourKey / userKey 汇总时的内存聚合键
+type hourKey struct {
+	bucket                               int64
+	channel, selfID, messageType, peerID string
+}
+
+type userKey struct {
+	day                              int64
+	channel, selfID, groupID, userID string
+}
+
+type userAgg struct {
+	name  string
+	count int64
+}
+
+// HourStart 毫秒时间戳所在小时的起点
+func HourStart(ms int64) int64 {
+	return ms - ms%int64(time.Hour/time.Millisecond)
+}
+
+// DayStart 毫秒时间戳所在自然日（服务器时区）的零点
+func DayStart(ms int64) int64 {
+	t := time.UnixMilli(ms)
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location()).UnixMilli()
+}
+
+// RollupStats 将上次汇总之后新增的 QQ 消息和其他通道的 message.* 事件累加到统计表，
+// 返回本次处理的行数。QQ 消息以 messages 表为准，按账号、群 / 好友和发送者统计
+func RollupStats(db *sql.DB) (int, error) {
+	total := 0
+	for {
+		n, err := rollupMessages(db)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < rollupBatch {
+			break
+		}
+	}
+	for {
+		n, err := rollupEvents(db)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < rollupBatch {
+			break
+		}
+	}
+	return total, nil
+}
+
+func rollupMessages(db *sql.DB) (int, error) {
+	mark := statsMark(db, statsMessagesMark)
+	rows, err := db.Query(
+		"SELECT id, self_id, message_type, peer_id, group_id, user_id, sender_card, sender_nickname, time FROM messages WHERE id > ? ORDER BY id LIMIT ?",
+		mark, rollupBatch,
+	)
+	if err != nil {
+		return 0, err
+	}
+	hours := map[hourKey][2]int64{}
+	users := map[userKey]*userAgg{}
+	n := 0
+	for rows.Next() {
+		var id, t int64
+		var selfID, msgType, peerID, groupID, userID, card, nickname string
+		if err := rows.Scan(&id, &selfID, &msgType, &peerID, &groupID, &userID, &card, &nickname, &t); err != nil {
+			continue
+		}
+		n++
+		mark = id
+		hk := hourKey{HourStart(t), "qq", selfID, msgType, peerID}
+		c := hours[hk]
+		if userID != "" && userID == selfID {
+			c[1]++
+			hours[hk] = c
+			continue
+		}
+		c[0]++
+		hours[hk] = c
+		uk := userKey{DayStart(t), "qq", selfID, groupID, userID}
+		ua := users[uk]
+		if ua == nil {
+			ua = &userAgg{}
+			users[uk] = ua
+		}
+		ua.count++
+		if name := firstNonEmptyString(card, nickname); name != "" {
+			ua.name = name
+		}
+	}
+	rows.Close()
+	if n == 0 {
+		return 0, nil
+	}
+	return n, saveRollup(db, statsMessagesMark, mark, hours, users)
+}
+
+// rollupEvents 统计非 QQ 通道（如外部推送的微信事件）的 message.<类型>.<sent|received> 事件；
+// QQ 收发消息已由 messages 表统计，这里跳过 qq / openclaw 来源
+func rollupEvents(db *sql.DB) (int, error) {
+	mark := statsMark(db, statsEventsMark)
+	rows, err := db.Query(
+		"SELECT id, source, type, time FROM events WHERE id > ? ORDER BY id LIMIT ?",
+		mark, rollupBatch,
+	)
+	if err != nil {
+		return 0, err
+	}
+	hours := map[hourKey][2]int64{}
+	n := 0
+	for rows.Next() {
+		var id, t int64
+		var source, typ string
+		if err := rows.Scan(&id, &source, &typ, &t); err != nil {
+			continue
+		}
+		n++
+		mark = id
+		if source == "qq" || source == "openclaw" || !strings.HasPrefix(typ, "message.") {
+			continue
+		}
+		parts := strings.Split(typ, ".")
+		if len(parts) != 3 {
+			continue
+		}
+		hk := hourKey{HourStart(t), source, "", parts[1], ""}
+		c := hours[hk]
+		switch parts[2] {
+		case "received":
+			c[0]++
+		case "sent":
+			c[1]++
+		default:
+			continue
+		}
+		hours[hk] = c
+	}
+	rows.Close()
+	if n == 0 {
+		return 0, nil
+	}
+	return n, saveRollup(db, statsEventsMark, mark, hours, nil)
+}
+
+// saveRollup 在一个事务中累加统计并推进汇总进度
+func saveRollup(db *sql.DB, markKey string, mark int64, hours map[hourKey][2]int64, users map[userKey]*userAgg) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for k, c := range hours {
+		if c[0] == 0 && c[1] == 0 {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO stats_hourly (bucket, channel, self_id, message_type, peer_id, received, sent) VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(bucket, channel, self_id, message_type, peer_id) DO UPDATE SET received = received + excluded.received, sent = sent + excluded.sent`,
+			k.bucket, k.channel, k.selfID, k.messageType, k.peerID, c[0], c[1],
+		); err != nil {
+			return err
+		}
+	}
+	for k, u := range users {
+		if _, err := tx.Exec(`
+			INSERT INTO stats_users_daily (day, channel, self_id, group_id, user_id, name, count) VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(day, channel, self_id, group_id, user_id) DO UPDATE SET count = count + excluded.count,
+				name = CASE WHEN excluded.name != '' THEN excluded.name ELSE name END`,
+			k.day, k.channel, k.selfID, k.groupID, k.userID, u.name, u.count,
+		); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP) ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP",
+		markKey, strconv.FormatInt(mark, 10),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func statsMark(db *sql.DB, key string) int64 {
+	v, _ := GetSetting(db, key)
+	n, _ := strconv.ParseInt(v, 10, 64)
+	return n
+}
+
+func firstNonEmptyString(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// hourlyWhere 根据筛选条件生成 stats_hourly 的 WHERE 子句
+func hourlyWhere(f StatsFilter) (string, []interface{}) {
+	where := "bucket >= ? AND bucket < ?"
+	args := []interface{}{HourStart(f.Since), f.Until}
+	if f.Channel != "" {
+		where += " AND channel = ?"
+		args = append(args, f.Channel)
+	}
+	if f.SelfID != "" {
+		where += " AND self_id = ?"
+		args = append(args, f.SelfID)
+	}
+	if f.GroupID != "" {
+		where += " AND message_type = 'group' AND peer_id = ?"
+		args = append(args, f.GroupID)
+	}
+	return where, args
+}
+
+// usersWhere 根据筛选条件生成 stats_users_daily 的 WHERE 子句，since 按天取整
+func usersWhere(f StatsFilter) (string, []interface{}) {
+	where := "day >= ? AND day < ?"
+	args := []interface{}{DayStart(f.Since), f.Until}
+	if f.Channel != "" {
+		where += " AND channel = ?"
+		args = append(args, f.Channel)
+	}
+	if f.SelfID != "" {
+		where += " AND self_id = ?"
+		args = append(args, f.SelfID)
+	}
+	if f.GroupID != "" {
+		where += " AND group_id = ?"
+		args = append(args, f.GroupID)
+	}
+	return where, args
+}
+
+// GetStatsSeries 按小时或按天（服务器时区）返回收发消息数，没有消息的时段补 0
+func GetStatsSeries(db *sql.DB, f StatsFilter, daily bool) ([]StatsPoint, error) {
+	where, args := hourlyWhere(f)
+	rows, err := db.Query("SELECT bucket, SUM(received), SUM(sent) FROM stats_hourly WHERE "+where+" GROUP BY bucket ORDER BY bucket", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	step := func(t int64) int64 { return t + int64(time.Hour/time.Millisecond) }
+	start := HourStart(f.Since)
+	if daily {
+		step = func(t int64) int64 { return time.UnixMilli(t).AddDate(0, 0, 1).UnixMilli() }
+		start = DayStart(f.Since)
+	}
+	var points []StatsPoint
+	index := map[int64]int{}
+	for t := start; t < f.Until; t = step(t) {
+		index[t] = len(points)
+		points = append(points, StatsPoint{Time: t})
+	}
+	for rows.Next() {
+		var bucket, received, sent int64
+		if err := rows.Scan(&bucket, &received, &sent); err != nil {
+			continue
+		}
+		key := bucket
+		if daily {
+			key = DayStart(bucket)
+		}
+		if i, ok := index[key]; ok {
+			points[i].Received += received
+			points[i].Sent += sent
+		}
+	}
+	if points == nil {
+		points = []StatsPoint{}
+	}
+	return points, nil
+}
+
+// GetChannelStats 按通道汇总收发消息数
+func GetChannelStats(db *sql.DB, f StatsFilter) ([]ChannelStats, error) {
+	where, args := hourlyWhere(f)
+	rows, err := db.Query("SELECT channel, SUM(received), SUM(sent) FROM stats_hourly WHERE "+where+" GROUP BY channel ORDER BY SUM(received + sent) DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []ChannelStats{}
+	for rows.Next() {
+		var s ChannelStats
+		if err := rows.Scan(&s.Channel, &s.Received, &s.Sent); err != nil {
+			continue
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// GetTopGroups 消息最多的群
+func GetTopGroups(db *sql.DB, f StatsFilter, limit int) ([]GroupStats, error) {
+	where, args := hourlyWhere(f)
+	rows, err := db.Query(
+		"SELECT peer_id, self_id, channel, SUM(received), SUM(sent) FROM stats_hourly WHERE "+where+
+			" AND message_type = 'group' AND peer_id != '' GROUP BY channel, self_id, peer_id ORDER BY SUM(received + sent) DESC LIMIT ?",
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []GroupStats{}
+	for rows.Next() {
+		var g GroupStats
+		if err := rows.Scan(&g.GroupID, &g.SelfID, &g.Channel, &g.Received, &g.Sent); err != nil {
+			continue
+		}
+		g.Total = g.Received + g.Sent
+		list = append(list, g)
+	}
+	return list, nil
+}
+
+// GetTopUsers 发言最多的用户（按自然日统计，since / until 按天取整），名称取最近一天的群名片或昵称
+func GetTopUsers(db *sql.DB, f StatsFilter, limit int) ([]UserStats, error) {
+	where, args := usersWhere(f)
+	rows, err := db.Query(fmt.Sprintf(
+		"SELECT user_id, name, channel, MAX(day), SUM(count) FROM stats_users_daily WHERE %s GROUP BY channel, user_id ORDER BY SUM(count) DESC LIMIT ?", where),
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []UserStats{}
+	for rows.Next() {
+		var u UserStats
+		var day int64
+		if err := rows.Scan(&u.UserID, &u.Name, &u.Channel, &day, &u.Count); err != nil {
+			continue
+		}
+		list = append(list, u)
+	}
+	return list, nil
+}
+
+// CountActive 时间范围内有消息的群数和发言用户数
+func CountActive(db *sql.DB, f StatsFilter) (groups, users int64, err error) {
+	where, args := hourlyWhere(f)
+	if err = db.QueryRow("SELECT COUNT(DISTINCT channel || ':' || self_id || ':' || peer_id) FROM stats_hourly WHERE "+where+" AND message_type = 'group' AND peer_id != ''", args...).Scan(&groups); err != nil {
+		return
+	}
+	uwhere, uargs := usersWhere(f)
+	err = db.QueryRow("SELECT COUNT(DISTINCT channel || ':' || user_id) FROM stats_users_daily WHERE "+uwhere, uargs...).Scan(&users)
+	return
+}
@@ -0,0 +1,236 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Webhook 外发 Webhook 配置
+type Webhook struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"-"`
+	HasSecret bool     `json:"hasSecret"`
+	Events    []string `json:"events"` // 事件类型通配符，如 process.*、request.*，为空匹配全部
+	Enabled   bool     `json:"enabled"`
+	CreatedAt int64    `json:"createdAt"`
+	UpdatedAt int64    `json:"updatedAt"`
+}
+
+// 投递状态
+const (
+	DeliveryPending = "pending"
+	DeliverySuccess = "success"
+	DeliveryFailed  = "failed" // 重试次数用尽
+)
+
+// WebhookDelivery 一次 Webhook 投递（含重试）
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhookId"`
+	EventID       int64           `json:"eventId"`
+	EventType     string          `json:"eventType"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt int64           `json:"nextAttemptAt,omitempty"`
+	StatusCode    int             `json:"statusCode,omitempty"` // 最近一次 HTTP 状态码
+	LastError     string          `json:"lastError,omitempty"`
+	CreatedAt     int64           `json:"createdAt"`
+	UpdatedAt     int64           `json:"updatedAt"`
+	DeliveredAt   int64           `json:"deliveredAt,omitempty"`
+	// 临时目标（webhookId 为 0），如告警规则单独配置的地址
+	TargetURL    string `json:"targetUrl,omitempty"`
+	TargetSecret string `json:"-"`
+}
+
+const webhookColumns = "id, name, url, secret, events, enabled, created_at, updated_at"
+
+const deliveryColumns = "id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, status_code, last_error, created_at, updated_at, delivered_at, target_url, target_secret"
+
+// GetWebhooks 获取全部 Webhook
+func GetWebhooks(db *sql.DB) ([]Webhook, error) {
+	rows, err := db.Query("SELECT " + webhookColumns + " FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			continue
+		}
+		hooks = append(hooks, *w)
+	}
+	return hooks, nil
+}
+
+// GetWebhook 按 ID 获取 Webhook
+func GetWebhook(db *sql.DB, id int64) (*Webhook, error) {
+	return scanWebhook(db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+}
+
+// SaveWebhook 新建（ID 为 0）或更新 Webhook
+func SaveWebhook(db *sql.DB, w *Webhook) error {
+	now := time.Now().UnixMilli()
+	events, _ := json.Marshal(nonNil(w.Events))
+	w.UpdatedAt = now
+	w.HasSecret = w.Secret != ""
+	if w.ID == 0 {
+		w.CreatedAt = now
+		result, err := db.Exec(
+			"INSERT INTO webhooks (name, url, secret, events, enabled, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			w.Name, w.URL, w.Secret, string(events), w.Enabled, w.CreatedAt, w.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		w.ID, _ = result.LastInsertId()
+		return nil
+	}
+	result, err := db.Exec(
+		"UPDATE webhooks SET name = ?, url = ?, secret = ?, events = ?, enabled = ?, updated_at = ? WHERE id = ?",
+		w.Name, w.URL, w.Secret, string(events), w.Enabled, w.UpdatedAt, w.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func DeleteWebhook(db *sql.DB, id int64) error {
+	result, err := db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = db.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id)
+	return err
+}
+
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	var w Webhook
+	var events string
+	if err := row.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, &events, &w.Enabled, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(events), &w.Events)
+	w.Events = nonNil(w.Events)
+	w.HasSecret = w.Secret != ""
+	return &w, nil
+}
+
+// AddDelivery 将一次投递加入队列，立即可发送
+func AddDelivery(db *sql.DB, d *WebhookDelivery) error {
+	now := time.Now().UnixMilli()
+	d.Status = DeliveryPending
+	d.CreatedAt, d.UpdatedAt, d.NextAttemptAt = now, now, now
+	result, err := db.Exec(
+		"INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, updated_at, target_url, target_secret) VALUES (?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?)",
+		d.WebhookID, d.EventID, d.EventType, string(d.Payload), d.Status, d.NextAttemptAt, d.CreatedAt, d.UpdatedAt, d.TargetURL, d.TargetSecret,
+	)
+	if err != nil {
+		return err
+	}
+	d.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetDueDeliveries 获取已到重试时间的待投递记录，按创建顺序
+func GetDueDeliveries(db *sql.DB, now int64, limit int) ([]WebhookDelivery, error) {
+	return queryDeliveries(db, "WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?", DeliveryPending, now, limit)
+}
+
+// GetDeliveries 查询投递记录（倒序），webhookID 为 0、status 为空时不筛选
+func GetDeliveries(db *sql.DB, webhookID int64, status string, limit, offset int) ([]WebhookDelivery, int, error) {
+	where := "WHERE 1=1"
+	args := []interface{}{}
+	if webhookID > 0 {
+		where += " AND webhook_id = ?"
+		args = append(args, webhookID)
+	}
+	if status != "" {
+		where += " AND status = ?"
+		args = append(args, status)
+	}
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM webhook_deliveries "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	list, err := queryDeliveries(db, where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	return list, total, err
+}
+
+// GetDelivery 按 ID 获取投递记录
+func GetDelivery(db *sql.DB, id int64) (*WebhookDelivery, error) {
+	list, err := queryDeliveries(db, "WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &list[0], nil
+}
+
+// FinishDelivery 记录一次投递尝试的结果。status 为 pending 时 nextAttemptAt 为下次重试时间
+func FinishDelivery(db *sql.DB, id int64, status string, statusCode int, lastErr string, nextAttemptAt int64) error {
+	now := time.Now().UnixMilli()
+	var deliveredAt int64
+	if status == DeliverySuccess {
+		deliveredAt = now
+	}
+	_, err := db.Exec(
+		"UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, status_code = ?, last_error = ?, next_attempt_at = ?, updated_at = ?, delivered_at = ? WHERE id = ?",
+		status, statusCode, lastErr, nextAttemptAt, now, deliveredAt, id,
+	)
+	return err
+}
+
+// RetryDelivery 将投递重新放回队列并清零重试次数
+func RetryDelivery(db *sql.DB, id int64) error {
+	now := time.Now().UnixMilli()
+	result, err := db.Exec(
+		"UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ? WHERE id = ?",
+		DeliveryPending, now, now, id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func queryDeliveries(db *sql.DB, clause string, args ...interface{}) ([]WebhookDelivery, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT %s FROM webhook_deliveries %s", deliveryColumns, clause), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload string
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.StatusCode, &d.LastError, &d.CreatedAt, &d.UpdatedAt, &d.DeliveredAt, &d.TargetURL, &d.TargetSecret); err != nil {
+			continue
+		}
+		d.Payload = json.RawMessage(payload)
+		list = append(list, d)
+	}
+	return list, nil
+}
@@ -0,0 +1,259 @@
+package napcat
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 凭证与登录重试参数
+const (
+	// CredentialTTL NapCat WebUI 凭证有效期，到期前主动重新登录
+	CredentialTTL = 50 * time.Minute
+	// loginAttempts 单次登录遇到网络错误时的最大尝试次数
+	loginAttempts = 3
+	// loginBackoff 登录重试的初始间隔，每次翻倍
+	loginBackoff = 300 * time.Millisecond
+	// maxCooldown 连续登录失败后暂停登录的最长时间
+	maxCooldown = time.Minute
+)
+
+var (
+	// ErrLoginRejected WebUI 拒绝登录（令牌错误或登录过于频繁）
+	ErrLoginRejected = errors.New("NapCat WebUI 登录被拒绝")
+	// ErrCoolingDown 最近登录失败，暂停重试中
+	ErrCoolingDown = errors.New("NapCat WebUI 登录失败，稍后重试")
+	// ErrUnauthorized 重新登录后 WebUI 仍拒绝凭证
+	ErrUnauthorized = errors.New("NapCat WebUI 凭证无效")
+)
+
+// Client NapCat WebUI API 客户端，并发安全。凭证缓存至过期，
+// 并发请求共享同一次登录；登录失败后按指数退避暂停重试，避免仪表盘轮询反复登录
+type Client struct {
+	baseURL func() string
+	token   func() string
+	http    *http.Client
+
+	mu         sync.Mutex
+	credential string
+	expiresAt  time.Time
+	inflight   *loginCall
+	failures   int
+	retryAt    time.Time
+	lastErr    error
+	// generation 地址或令牌变更（Invalidate("")）时递增，变更前发起的登录结果不再缓存
+	generation uint64
+}
+
+// loginCall 一次进行中的登录，等待者读取其结果
+type loginCall struct {
+	done       chan struct{}
+	credential string
+	err        error
+}
+
+// NewClient 创建 NapCat WebUI 客户端，地址和令牌每次登录时读取，以便运行时修改
+func NewClient(baseURL, token func() string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		token:   token,
+		http:    &http.Client{},
+	}
+}
+
+// BaseURL 当前 WebUI 地址
+func (c *Client) BaseURL() string {
+	return strings.TrimRight(c.baseURL(), "/")
+}
+
+// Call 调用 WebUI API，凭证失效时重新登录并重试一次
+func (c *Client) Call(method, path string, body interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	cred, err := c.Credential()
+	if err != nil {
+		return nil, err
+	}
+	r, status, err := c.do(method, path, body, cred, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if isUnauthorized(r, status) {
+		c.Invalidate(cred)
+		if cred, err = c.Credential(); err != nil {
+			return nil, err
+		}
+		r, status, err = c.do(method, path, body, cred, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if isUnauthorized(r, status) {
+			c.Invalidate(cred)
+			return nil, ErrUnauthorized
+		}
+	}
+	return r, nil
+}
+
+// Credential 返回有效凭证，必要时登录。并发调用只会触发一次登录
+func (c *Client) Credential() (string, error) {
+	c.mu.Lock()
+	now := time.Now()
+	if c.credential != "" && now.Before(c.expiresAt) {
+		cred := c.credential
+		c.mu.Unlock()
+		return cred, nil
+	}
+	if call := c.inflight; call != nil {
+		c.mu.Unlock()
+		<-call.done
+		return call.credential, call.err
+	}
+	if now.Before(c.retryAt) {
+		err := c.lastErr
+		c.mu.Unlock()
+		return "", fmt.Errorf("%w: %v", ErrCoolingDown, err)
+	}
+	call := &loginCall{done: make(chan struct{})}
+	c.inflight = call
+	generation := c.generation
+	c.mu.Unlock()
+
+	call.credential, call.err = c.loginWithRetry()
+
+	c.mu.Lock()
+	if c.inflight == call {
+		c.inflight = nil
+	}
+	switch {
+	case generation != c.generation:
+		// 登录期间地址或令牌已变更，结果属于旧配置，不缓存也不计入失败
+	case call.err == nil:
+		c.credential = call.credential
+		c.expiresAt = time.Now().Add(CredentialTTL)
+		c.failures = 0
+		c.retryAt = time.Time{}
+		c.lastErr = nil
+	default:
+		c.credential = ""
+		c.failures++
+		c.retryAt = time.Now().Add(cooldown(c.failures))
+		c.lastErr = call.err
+	}
+	c.mu.Unlock()
+	close(call.done)
+	return call.credential, call.err
+}
+
+// Invalidate 丢弃凭证。stale 非空时仅当缓存的仍是该凭证才丢弃，避免覆盖其他请求刚取得的新凭证
+func (c *Client) Invalidate(stale string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stale == "" || c.credential == stale {
+		c.credential = ""
+		c.expiresAt = time.Time{}
+	}
+	if stale == "" {
+		// 地址或令牌变更后允许立即重新登录，进行中的登录作废
+		c.failures = 0
+		c.retryAt = time.Time{}
+		c.generation++
+		c.inflight = nil
+	}
+}
+
+// loginWithRetry 登录，网络错误时按指数退避重试，被 WebUI 拒绝时直接返回
+func (c *Client) loginWithRetry() (string, error) {
+	var err error
+	delay := loginBackoff
+	for i := 0; i < loginAttempts; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		var cred string
+		cred, err = c.login()
+		if err == nil || errors.Is(err, ErrLoginRejected) {
+			return cred, err
+		}
+	}
+	return "", err
+}
+
+// login 以 sha256(token + ".napcat") 调用 /api/auth/login 换取 Credential
+func (c *Client) login() (string, error) {
+	hash := sha256.Sum256([]byte(c.token() + ".napcat"))
+	r, _, err := c.do("POST", "/api/auth/login", map[string]string{"hash": fmt.Sprintf("%x", hash)}, "", 15*time.Second)
+	if err != nil {
+		return "", err
+	}
+	if code, ok := r["code"].(float64); !ok || code != 0 {
+		msg, _ := r["message"].(string)
+		return "", fmt.Errorf("%w: %s", ErrLoginRejected, msg)
+	}
+	data, _ := r["data"].(map[string]interface{})
+	cred, _ := data["Credential"].(string)
+	if cred == "" {
+		return "", fmt.Errorf("%w: 响应缺少 Credential", ErrLoginRejected)
+	}
+	return cred, nil
+}
+
+// do 发送请求，非 JSON 响应以 {"raw": ...} 返回
+func (c *Client) do(method, path string, body interface{}, credential string, timeout time.Duration) (map[string]interface{}, int, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		bodyReader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.BaseURL()+path, bodyReader)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if credential != "" {
+		req.Header.Set("Authorization", "Bearer "+credential)
+	}
+	client := *c.http
+	client.Timeout = timeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return map[string]interface{}{"raw": string(data)}, resp.StatusCode, nil
+	}
+	return result, resp.StatusCode, nil
+}
+
+// isUnauthorized WebUI 以 HTTP 401 或 code=-1 + Unauthorized 表示凭证失效
+func isUnauthorized(r map[string]interface{}, status int) bool {
+	if status == http.StatusUnauthorized {
+		return true
+	}
+	if code, ok := r["code"].(float64); ok && code == -1 {
+		msg, _ := r["message"].(string)
+		return strings.Contains(strings.ToLower(msg), "unauthorized")
+	}
+	return false
+}
+
+// cooldown 连续失败 n 次后的暂停时间：1s, 2s, 4s ... 最长 maxCooldown
+func cooldown(n int) time.Duration {
+	d := time.Second
+	for i := 1; i < n && d < maxCooldown; i++ {
+		d *= 2
+	}
+	if d > maxCooldown {
+		d = maxCooldown
+	}
+	return d
+}
@@ -0,0 +1,191 @@
+package napcat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeWebUI 模拟 NapCat WebUI：/api/auth/login 每次签发新凭证，其余接口校验 Bearer 凭证
+type fakeWebUI struct {
+	logins     atomic.Int32
+	calls      atomic.Int32
+	loginDelay time.Duration
+	rejectAll  bool // 登录返回 code != 0
+	always401  bool // 业务接口总是返回 401
+
+	mu    sync.Mutex
+	valid string
+}
+
+func (f *fakeWebUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api/auth/login" {
+		n := f.logins.Add(1)
+		time.Sleep(f.loginDelay)
+		if f.rejectAll {
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": -1, "message": "token error"})
+			return
+		}
+		cred := fmt.Sprintf("cred-%d", n)
+		f.mu.Lock()
+		f.valid = cred
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "data": map[string]string{"Credential": cred}})
+		return
+	}
+	f.calls.Add(1)
+	f.mu.Lock()
+	ok := r.Header.Get("Authorization") == "Bearer "+f.valid
+	f.mu.Unlock()
+	if f.always401 || !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": -1, "message": "Unauthorized"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "data": "ok"})
+}
+
+func newTestClient(t *testing.T, f *fakeWebUI) *Client {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return NewClient(func() string { return srv.URL }, func() string { return "token" })
+}
+
+func TestCredentialSingleFlight(t *testing.T) {
+	f := &fakeWebUI{loginDelay: 50 * time.Millisecond}
+	c := newTestClient(t, f)
+
+	var wg sync.WaitGroup
+	creds := make([]string, 10)
+	errs := make([]error, 10)
+	for i := range creds {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			creds[i], errs[i] = c.Credential()
+		}(i)
+	}
+	wg.Wait()
+
+	if n := f.logins.Load(); n != 1 {
+		t.Fatalf("logins = %d, want 1", n)
+	}
+	for i := range creds {
+		if errs[i] != nil || creds[i] != "cred-1" {
+			t.Errorf("caller %d: credential = %q, err = %v", i, creds[i], errs[i])
+		}
+	}
+}
+
+func TestCredentialRefreshAfterExpiry(t *testing.T) {
+	f := &fakeWebUI{}
+	c := newTestClient(t, f)
+
+	if cred, err := c.Credential(); err != nil || cred != "cred-1" {
+		t.Fatalf("first credential = %q, %v", cred, err)
+	}
+	if cred, _ := c.Credential(); cred != "cred-1" {
+		t.Fatalf("cached credential = %q, want cred-1", cred)
+	}
+
+	c.mu.Lock()
+	c.expiresAt = time.Now().Add(-time.Second)
+	c.mu.Unlock()
+
+	if cred, err := c.Credential(); err != nil || cred != "cred-2" {
+		t.Fatalf("refreshed credential = %q, %v", cred, err)
+	}
+	if n := f.logins.Load(); n != 2 {
+		t.Errorf("logins = %d, want 2", n)
+	}
+}
+
+func TestCallReloginOnceOn401(t *testing.T) {
+	f := &fakeWebUI{}
+	c := newTestClient(t, f)
+
+	// 凭证被 WebUI 重启作废：重新登录一次后成功
+	if _, err := c.Credential(); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.valid = ""
+	f.mu.Unlock()
+	r, err := c.Call("POST", "/api/QQLogin/CheckLoginStatus", nil, time.Second)
+	if err != nil || r["data"] != "ok" {
+		t.Fatalf("call = %v, %v", r, err)
+	}
+	if logins, calls := f.logins.Load(), f.calls.Load(); logins != 2 || calls != 2 {
+		t.Fatalf("logins = %d, calls = %d, want 2 / 2", logins, calls)
+	}
+
+	// 重试后仍然 401：不再循环登录，返回 ErrUnauthorized
+	f.always401 = true
+	f.logins.Store(0)
+	f.calls.Store(0)
+	if _, err := c.Call("POST", "/api/QQLogin/CheckLoginStatus", nil, time.Second); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("call err = %v, want ErrUnauthorized", err)
+	}
+	if logins, calls := f.logins.Load(), f.calls.Load(); logins != 1 || calls != 2 {
+		t.Errorf("logins = %d, calls = %d, want 1 / 2", logins, calls)
+	}
+}
+
+func TestInvalidateDuringLogin(t *testing.T) {
+	f := &fakeWebUI{loginDelay: 100 * time.Millisecond}
+	c := newTestClient(t, f)
+
+	// 登录进行中修改了地址：旧登录的凭证不缓存，之后重新登录
+	done := make(chan struct{})
+	go func() {
+		c.Credential()
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	c.Invalidate("")
+	<-done
+	c.mu.Lock()
+	cached := c.credential
+	c.mu.Unlock()
+	if cached != "" {
+		t.Fatalf("cached credential = %q, want none", cached)
+	}
+	if cred, err := c.Credential(); err != nil || cred != "cred-2" {
+		t.Fatalf("credential = %q, %v, want cred-2", cred, err)
+	}
+}
+
+func TestCredentialCoolingDown(t *testing.T) {
+	f := &fakeWebUI{rejectAll: true}
+	c := newTestClient(t, f)
+
+	if _, err := c.Credential(); !errors.Is(err, ErrLoginRejected) {
+		t.Fatalf("first login err = %v, want ErrLoginRejected", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.Credential(); !errors.Is(err, ErrCoolingDown) {
+			t.Fatalf("retry %d err = %v, want ErrCoolingDown", i, err)
+		}
+	}
+	if _, err := c.Call("GET", "/api/QQLogin/CheckLoginStatus", nil, time.Second); !errors.Is(err, ErrCoolingDown) {
+		t.Fatalf("call err = %v, want ErrCoolingDown", err)
+	}
+	if n := f.logins.Load(); n != 1 {
+		t.Errorf("logins = %d, want 1 (no login during cooldown)", n)
+	}
+
+	// 冷却结束后允许再次登录
+	f.rejectAll = false
+	c.mu.Lock()
+	c.retryAt = time.Now().Add(-time.Millisecond)
+	c.mu.Unlock()
+	if cred, err := c.Credential(); err != nil || cred != "cred-2" {
+		t.Fatalf("after cooldown credential = %q, %v", cred, err)
+	}
+}
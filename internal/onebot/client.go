@@ -0,0 +1,139 @@
+package onebot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Response OneBot11 HTTP API 标准响应
+type Response struct {
+	Status  string          `json:"status"`
+	Retcode int             `json:"retcode"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+	Wording string          `json:"wording"`
+}
+
+// ErrUnauthorized access_token 缺失或错误（HTTP 401 / 403）
+var ErrUnauthorized = errors.New("OneBot access_token 无效")
+
+// Client OneBot11 HTTP API 客户端
+type Client struct {
+	baseURL    func() string
+	token      func() string
+	http       *http.Client
+	onAuthFail func(err error)
+}
+
+// NewClient 创建 OneBot11 HTTP API 客户端，每次调用时通过 baseURL / token 获取地址和 access_token，以便运行时修改。
+// token 可为 nil
+func NewClient(baseURL, token func() string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		token:   token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// OnAuthFailure 设置鉴权失败回调，用于记录活动日志
+func (c *Client) OnAuthFailure(fn func(err error)) {
+	c.onAuthFail = fn
+}
+
+// BaseURL 当前 API 地址
+func (c *Client) BaseURL() string {
+	return strings.TrimRight(c.baseURL(), "/")
+}
+
+// Call 调用 OneBot11 action，校验 retcode 并将 data 解析到 out（out 可为 nil）
+func (c *Client) Call(action string, params interface{}, out interface{}) error {
+	var bodyReader io.Reader
+	if params != nil {
+		data, _ := json.Marshal(params)
+		bodyReader = bytes.NewReader(data)
+	} else {
+		bodyReader = strings.NewReader("{}")
+	}
+	req, err := http.NewRequest("POST", c.BaseURL()+"/"+strings.TrimPrefix(action, "/"), bodyReader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != nil {
+		if token := c.token(); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		err := fmt.Errorf("%w: %s HTTP %d", ErrUnauthorized, action, resp.StatusCode)
+		if c.onAuthFail != nil {
+			c.onAuthFail(err)
+		}
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OneBot HTTP %d: %s", resp.StatusCode, truncate(string(data), 200))
+	}
+	var r Response
+	if err := json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("OneBot 响应解析失败: %w", err)
+	}
+	if r.Status == "failed" || r.Retcode != 0 {
+		msg := r.Wording
+		if msg == "" {
+			msg = r.Message
+		}
+		return fmt.Errorf("OneBot %s 失败 (retcode=%d): %s", action, r.Retcode, msg)
+	}
+	if out != nil && len(r.Data) > 0 {
+		if err := json.Unmarshal(r.Data, out); err != nil {
+			return fmt.Errorf("OneBot 数据解析失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetFriendAddRequest 处理加好友请求，remark 仅在同意时生效
+func (c *Client) SetFriendAddRequest(flag string, approve bool, remark string) error {
+	params := map[string]interface{}{
+		"flag":    flag,
+		"approve": approve,
+	}
+	if approve && remark != "" {
+		params["remark"] = remark
+	}
+	return c.Call("set_friend_add_request", params, nil)
+}
+
+// SetGroupAddRequest 处理加群请求/邀请，reason 仅在拒绝时生效
+func (c *Client) SetGroupAddRequest(flag, subType string, approve bool, reason string) error {
+	params := map[string]interface{}{
+		"flag":     flag,
+		"sub_type": subType,
+		"approve":  approve,
+	}
+	if !approve && reason != "" {
+		params["reason"] = reason
+	}
+	return c.Call("set_group_add_request", params, nil)
+}
+
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen]) + "..."
+}
@@ -198,7 +198,7 @@ func (m *Manager) StreamLogs(hub *websocket.Hub) {
 			m.logMu.RUnlock()
 
 			for _, line := range newLines {
-				hub.Broadcast([]byte(line))
+				hub.Publish(websocket.TopicProcessLogs, []byte(line))
 			}
 		}
 	}
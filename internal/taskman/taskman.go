@@ -214,7 +214,7 @@ func (m *Manager) broadcastTaskUpdate(task *Task) {
 	if err != nil {
 		return
 	}
-	m.hub.Broadcast(data)
+	m.hub.Publish(websocket.TopicTaskPrefix+task.ID, data)
 }
 
 // broadcastTaskLog 广播任务日志行
@@ -228,7 +228,7 @@ func (m *Manager) broadcastTaskLog(task *Task, line string) {
 	if err != nil {
 		return
 	}
-	m.hub.Broadcast(data)
+	m.hub.Publish(websocket.TopicTaskPrefix+task.ID, data)
 }
 
 // FinishTask 完成任务
@@ -0,0 +1,362 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhaoxinyi02/ClawPanel/internal/model"
+)
+
+// 投递与重试参数
+const (
+	// MaxAttempts 单次投递最多尝试次数，用尽后标记为 failed
+	MaxAttempts = 8
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+	// pollInterval 轮询队列的周期，新事件入队时会立即唤醒
+	pollInterval = 5 * time.Second
+	batchSize    = 50
+	concurrency  = 4
+)
+
+// 请求头
+const (
+	HeaderEvent     = "X-ClawPanel-Event"
+	HeaderDelivery  = "X-ClawPanel-Delivery"
+	HeaderTimestamp = "X-ClawPanel-Timestamp"
+	HeaderSignature = "X-ClawPanel-Signature"
+)
+
+// Dispatcher 将面板事件按类型过滤后写入 SQLite 投递队列，由后台协程签名发送并按指数退避重试。
+// 队列持久化，面板重启后未完成的投递会继续发送
+type Dispatcher struct {
+	db     *sql.DB
+	client *http.Client
+
+	mu    sync.RWMutex
+	hooks []model.Webhook
+
+	onFailure func(hook model.Webhook, d model.WebhookDelivery)
+
+	wake   chan struct{}
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// NewDispatcher 创建 Webhook 分发器并加载配置
+func NewDispatcher(db *sql.DB) *Dispatcher {
+	d := &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: 10 * time.Second},
+		wake:   make(chan struct{}, 1),
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := d.Reload(); err != nil {
+		log.Printf("[Webhook] 加载配置失败: %v", err)
+	}
+	return d
+}
+
+// OnFailure 设置投递最终失败（重试次数用尽）时的回调
+func (d *Dispatcher) OnFailure(fn func(hook model.Webhook, delivery model.WebhookDelivery)) {
+	d.onFailure = fn
+}
+
+// Reload 从数据库重新加载 Webhook 配置，增删改后调用
+func (d *Dispatcher) Reload() error {
+	hooks, err := model.GetWebhooks(d.db)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.hooks = hooks
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *Dispatcher) hook(id int64) (model.Webhook, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, h := range d.hooks {
+		if h.ID == id {
+			return h, true
+		}
+	}
+	return model.Webhook{}, false
+}
+
+// target 投递目标：临时地址或已登记的 Webhook
+func (d *Dispatcher) target(delivery model.WebhookDelivery) (model.Webhook, bool) {
+	if delivery.WebhookID == 0 && delivery.TargetURL != "" {
+		return model.Webhook{Name: delivery.TargetURL, URL: delivery.TargetURL, Secret: delivery.TargetSecret, Enabled: true}, true
+	}
+	return d.hook(delivery.WebhookID)
+}
+
+// Match 判断事件类型是否匹配 Webhook 的过滤规则，规则为空时匹配全部
+func Match(patterns []string, eventType string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if p == eventType {
+			return true
+		}
+		if ok, _ := path.Match(p, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatch 将事件加入所有匹配 Webhook 的投递队列。d 为 nil 时不做任何事；
+// webhook.* 事件不外发，避免投递失败日志再次触发投递
+func (d *Dispatcher) Dispatch(e *model.Event) {
+	if d == nil || e == nil || strings.HasPrefix(e.Type, "webhook.") {
+		return
+	}
+	d.mu.RLock()
+	hooks := d.hooks
+	d.mu.RUnlock()
+
+	queued := false
+	for _, h := range hooks {
+		if !h.Enabled || !Match(h.Events, e.Type) {
+			continue
+		}
+		if _, err := d.enqueue(h.ID, e); err != nil {
+			log.Printf("[Webhook] 入队失败 (webhook %d): %v", h.ID, err)
+			continue
+		}
+		queued = true
+	}
+	if queued {
+		d.Wake()
+	}
+}
+
+// DispatchTo 将事件投递到未登记的临时地址（如告警规则单独配置的地址），
+// 与已登记的 Webhook 一样签名、持久化并重试。d 为 nil 时不做任何事
+func (d *Dispatcher) DispatchTo(url, secret string, e *model.Event) {
+	if d == nil || e == nil || url == "" {
+		return
+	}
+	payload, err := eventPayload(e)
+	if err != nil {
+		log.Printf("[Webhook] 入队失败 (%s): %v", url, err)
+		return
+	}
+	delivery := &model.WebhookDelivery{
+		EventID:      e.ID,
+		EventType:    e.Type,
+		Payload:      payload,
+		TargetURL:    url,
+		TargetSecret: secret,
+	}
+	if err := model.AddDelivery(d.db, delivery); err != nil {
+		log.Printf("[Webhook] 入队失败 (%s): %v", url, err)
+		return
+	}
+	d.Wake()
+}
+
+// Test 向指定 Webhook 投递一条 webhook.test 测试事件（不受过滤规则限制）
+func (d *Dispatcher) Test(hook model.Webhook) (*model.WebhookDelivery, error) {
+	e := &model.Event{
+		Time:     time.Now().UnixMilli(),
+		Source:   "system",
+		Type:     "webhook.test",
+		Summary:  fmt.Sprintf("Webhook 测试: %s", hook.Name),
+		Severity: model.SeverityInfo,
+	}
+	delivery, err := d.enqueue(hook.ID, e)
+	if err != nil {
+		return nil, err
+	}
+	d.Wake()
+	return delivery, nil
+}
+
+func (d *Dispatcher) enqueue(hookID int64, e *model.Event) (*model.WebhookDelivery, error) {
+	payload, err := eventPayload(e)
+	if err != nil {
+		return nil, err
+	}
+	delivery := &model.WebhookDelivery{
+		WebhookID: hookID,
+		EventID:   e.ID,
+		EventType: e.Type,
+		Payload:   payload,
+	}
+	if err := model.AddDelivery(d.db, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// eventPayload 投递内容：{"type": 事件类型, "event": 事件}
+func eventPayload(e *model.Event) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":  e.Type,
+		"event": e,
+	})
+}
+
+// Wake 唤醒发送协程立即处理队列（新事件入队、手动重试后）
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start 启动后台发送协程
+func (d *Dispatcher) Start() {
+	go d.run()
+}
+
+// Stop 停止后台发送协程，等待当前批次发送完成
+func (d *Dispatcher) Stop() {
+	close(d.stopCh)
+	<-d.done
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.flush()
+		select {
+		case <-d.stopCh:
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// flush 发送所有已到时间的投递，每批最多 batchSize 条、并发 concurrency 个
+func (d *Dispatcher) flush() {
+	for {
+		due, err := model.GetDueDeliveries(d.db, time.Now().UnixMilli(), batchSize)
+		if err != nil {
+			log.Printf("[Webhook] 读取投递队列失败: %v", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		for _, delivery := range due {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(delivery model.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				d.attempt(delivery)
+			}(delivery)
+		}
+		wg.Wait()
+		if len(due) < batchSize {
+			return
+		}
+		select {
+		case <-d.stopCh:
+			return
+		default:
+		}
+	}
+}
+
+// attempt 发送一次投递并记录结果，失败时安排下次重试
+func (d *Dispatcher) attempt(delivery model.WebhookDelivery) {
+	hook, ok := d.target(delivery)
+	if !ok || !hook.Enabled {
+		model.FinishDelivery(d.db, delivery.ID, model.DeliveryFailed, 0, "webhook deleted or disabled", 0)
+		return
+	}
+
+	statusCode, err := d.send(hook, delivery)
+	if err == nil {
+		model.FinishDelivery(d.db, delivery.ID, model.DeliverySuccess, statusCode, "", 0)
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	if attempts >= MaxAttempts {
+		log.Printf("[Webhook] 投递 %d 最终失败 (%s): %v", delivery.ID, hook.URL, err)
+		model.FinishDelivery(d.db, delivery.ID, model.DeliveryFailed, statusCode, err.Error(), 0)
+		if d.onFailure != nil {
+			delivery.Attempts, delivery.StatusCode, delivery.LastError = attempts, statusCode, err.Error()
+			d.onFailure(hook, delivery)
+		}
+		return
+	}
+	next := time.Now().Add(Backoff(attempts))
+	model.FinishDelivery(d.db, delivery.ID, model.DeliveryPending, statusCode, err.Error(), next.UnixMilli())
+}
+
+// Backoff 第 n 次失败后的等待时间：10s, 20s, 40s ... 最长 1 小时
+func Backoff(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+	b := baseBackoff << uint(n-1)
+	if b > maxBackoff || b <= 0 {
+		return maxBackoff
+	}
+	return b
+}
+
+// send 签名并 POST 投递内容，2xx 视为成功
+func (d *Dispatcher) send(hook model.Webhook, delivery model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ClawPanel-Webhook")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if hook.Secret != "" {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(hook.Secret, timestamp, delivery.Payload))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body) 的十六进制
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
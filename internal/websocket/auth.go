@@ -0,0 +1,133 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	ws "github.com/gorilla/websocket"
+)
+
+// authTimeout 未在 URL 中携带令牌时，等待首条认证消息的时间
+const authTimeout = 10 * time.Second
+
+// 关闭连接时的状态码
+const (
+	CloseUnauthorized = 4001 // 未认证或令牌无效
+	CloseTokenExpired = 4002 // 令牌已过期
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+// SetAuth 设置令牌校验函数，返回令牌的过期时间（零值表示不过期）。设置后所有连接都需要认证
+func (h *Hub) SetAuth(authenticate func(token string) (time.Time, error)) {
+	h.authenticate = authenticate
+}
+
+// SetAllowedOrigins 设置跨域来源白名单，每次握手时读取，"*" 表示允许所有来源
+func (h *Hub) SetAllowedOrigins(origins func() []string) {
+	h.allowedOrigins = origins
+}
+
+// checkOrigin 允许无 Origin 头的非浏览器客户端、同源请求和白名单中的来源
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if h.allowedOrigins != nil {
+		for _, o := range h.allowedOrigins() {
+			if o == "*" || strings.EqualFold(strings.TrimRight(o, "/"), origin) {
+				return true
+			}
+		}
+	}
+	log.Printf("[WebSocket] 拒绝来源: %s", origin)
+	return false
+}
+
+func tokenFromRequest(c *gin.Context) string {
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token != "" {
+		return token
+	}
+	return c.Query("token")
+}
+
+// handshake 读取连接后的第一条 {"type":"auth","token":"..."} 消息并校验，失败时关闭连接
+func (h *Hub) handshake(conn *ws.Conn) (time.Time, error) {
+	conn.SetReadDeadline(time.Now().Add(authTimeout))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		conn.Close()
+		return time.Time{}, err
+	}
+	var req clientMessage
+	if json.Unmarshal(data, &req) != nil || req.Type != "auth" || req.Token == "" {
+		closeConn(conn, CloseUnauthorized, "authentication required")
+		return time.Time{}, errUnauthorized
+	}
+	expires, err := h.authenticate(req.Token)
+	if err != nil {
+		closeConn(conn, CloseUnauthorized, "invalid token")
+		return time.Time{}, err
+	}
+	conn.SetReadDeadline(time.Time{})
+	conn.WriteJSON(authenticated(expires))
+	return expires, nil
+}
+
+// reauthenticate 处理连接期间的 auth 消息，令牌有效时顺延过期时间
+func (c *Client) reauthenticate(token string) {
+	if c.hub.authenticate == nil {
+		return
+	}
+	expires, err := c.hub.authenticate(token)
+	if err != nil {
+		c.reply(map[string]interface{}{"type": "auth-failed", "error": "invalid token"})
+		return
+	}
+	c.expireAt(expires)
+	c.reply(authenticated(expires))
+}
+
+// expireAt 令牌到期时以 4002 关闭连接，零值表示不过期
+func (c *Client) expireAt(expires time.Time) {
+	c.expiryMu.Lock()
+	defer c.expiryMu.Unlock()
+	if c.expiry != nil {
+		c.expiry.Stop()
+		c.expiry = nil
+	}
+	if expires.IsZero() {
+		return
+	}
+	c.expiry = time.AfterFunc(time.Until(expires), func() {
+		closeConn(c.conn, CloseTokenExpired, "token expired")
+	})
+}
+
+func (c *Client) stopExpiry() {
+	c.expireAt(time.Time{})
+}
+
+func authenticated(expires time.Time) map[string]interface{} {
+	msg := map[string]interface{}{"type": "authenticated"}
+	if !expires.IsZero() {
+		msg["expiresAt"] = expires.UnixMilli()
+	}
+	return msg
+}
+
+// closeConn 发送关闭帧后断开连接
+func closeConn(conn *ws.Conn, code int, reason string) {
+	conn.WriteControl(ws.CloseMessage, ws.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	conn.Close()
+}
@@ -1,46 +1,82 @@
 package websocket
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
+	"path"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	ws "github.com/gorilla/websocket"
 )
 
-var upgrader = ws.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // 允许所有来源
-	},
-}
+// 常用主题。客户端订阅时可使用通配符，如 task:*、events:*
+const (
+	TopicProcessLogs = "process.logs"
+	TopicTaskPrefix  = "task:"   // task:<id> 任务状态和日志
+	TopicEventPrefix = "events:" // events:<source> 活动日志，如 events:qq、events:system
+)
 
 // Client WebSocket 客户端
 type Client struct {
 	hub  *Hub
 	conn *ws.Conn
 	send chan []byte
+
+	// 订阅的主题（可含通配符），nil 表示从未订阅，接收全部消息（兼容旧客户端）。
+	// 由 hub.mu 保护
+	topics map[string]bool
+
+	// 令牌到期时关闭连接，客户端重新认证后顺延
+	expiryMu sync.Mutex
+	expiry   *time.Timer
+}
+
+// message 待分发的消息，topic 为空时发给所有客户端
+type message struct {
+	topic string
+	data  []byte
+}
+
+// clientMessage 客户端发送的认证、订阅 / 取消订阅请求
+type clientMessage struct {
+	Type   string   `json:"type"` // auth, subscribe, unsubscribe
+	Token  string   `json:"token"`
+	Topics []string `json:"topics"`
 }
 
 // Hub WebSocket 消息中心
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan message
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+
+	upgrader ws.Upgrader
+	// authenticate 校验令牌并返回过期时间（零值表示不过期），为 nil 时不要求认证
+	authenticate func(token string) (time.Time, error)
+	// allowedOrigins 跨域来源白名单，同源请求总是允许
+	allowedOrigins func() []string
 }
 
 // NewHub 创建 WebSocket Hub
 func NewHub() *Hub {
-	return &Hub{
+	h := &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan message, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 	}
+	h.upgrader = ws.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
+	}
+	return h
 }
 
 // Run 运行 Hub 消息循环
@@ -62,39 +98,134 @@ func (h *Hub) Run() {
 			h.mu.Unlock()
 			log.Printf("[WebSocket] 客户端已断开，当前连接数: %d", len(h.clients))
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
+		case msg := <-h.broadcast:
+			h.mu.Lock()
 			for client := range h.clients {
+				if !client.subscribed(msg.topic) {
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- msg.data:
 				default:
 					// 发送缓冲区满，断开客户端
 					close(client.send)
 					delete(h.clients, client)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
 
 // Broadcast 广播消息给所有客户端
 func (h *Hub) Broadcast(msg []byte) {
+	h.Publish("", msg)
+}
+
+// Publish 将消息发给订阅了 topic 的客户端，以及从未订阅过的客户端
+func (h *Hub) Publish(topic string, msg []byte) {
 	select {
-	case h.broadcast <- msg:
+	case h.broadcast <- message{topic: topic, data: msg}:
 	default:
 		// 广播通道满，丢弃消息
 	}
 }
 
-// HandleWebSocket 处理 WebSocket 连接的 Gin handler
+// subscribed 客户端是否接收该主题，调用方持有 hub.mu
+func (c *Client) subscribed(topic string) bool {
+	if topic == "" || c.topics == nil {
+		return true
+	}
+	for p := range c.topics {
+		if p == topic {
+			return true
+		}
+		if ok, _ := path.Match(p, topic); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// handleMessage 处理客户端发来的消息，其余内容忽略
+func (c *Client) handleMessage(data []byte) {
+	var req clientMessage
+	if json.Unmarshal(data, &req) != nil {
+		return
+	}
+	switch req.Type {
+	case "auth":
+		c.reauthenticate(req.Token)
+	case "subscribe", "unsubscribe":
+		c.handleSubscription(req)
+	}
+}
+
+// handleSubscription 处理订阅请求，并回复 {"type":"subscribed","topics":[...]} 告知当前订阅
+func (c *Client) handleSubscription(req clientMessage) {
+	h := c.hub
+	h.mu.Lock()
+	if c.topics == nil {
+		c.topics = map[string]bool{}
+	}
+	for _, t := range req.Topics {
+		if _, err := path.Match(t, ""); t == "" || err != nil {
+			continue
+		}
+		if req.Type == "subscribe" {
+			c.topics[t] = true
+		} else {
+			delete(c.topics, t)
+		}
+	}
+
+	topics := make([]string, 0, len(c.topics))
+	for t := range c.topics {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	h.mu.Unlock()
+	c.reply(map[string]interface{}{"type": "subscribed", "topics": topics})
+}
+
+// reply 向客户端发送一条 JSON 消息，连接已注销时丢弃
+func (c *Client) reply(v interface{}) {
+	data, _ := json.Marshal(v)
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; ok {
+		select {
+		case c.send <- data:
+		default:
+		}
+	}
+}
+
+// HandleWebSocket 处理 WebSocket 连接的 Gin handler。设置了认证时，令牌通过 ?token=、
+// Authorization 头或连接后的第一条 {"type":"auth","token":"..."} 消息提供
 func (h *Hub) HandleWebSocket() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		token := tokenFromRequest(c)
+		var expires time.Time
+		if h.authenticate != nil && token != "" {
+			var err error
+			if expires, err = h.authenticate(token); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"ok": false, "error": "认证令牌无效或已过期"})
+				return
+			}
+		}
+
+		conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			log.Printf("[WebSocket] 升级失败: %v", err)
 			return
 		}
+		if h.authenticate != nil && token == "" {
+			if expires, err = h.handshake(conn); err != nil {
+				return
+			}
+		}
 
 		client := &Client{
 			hub:  h,
@@ -103,6 +234,7 @@ func (h *Hub) HandleWebSocket() gin.HandlerFunc {
 		}
 
 		h.register <- client
+		client.expireAt(expires)
 
 		// 启动读写协程
 		go client.writePump()
@@ -110,18 +242,20 @@ func (h *Hub) HandleWebSocket() gin.HandlerFunc {
 	}
 }
 
-// readPump 读取客户端消息（主要用于检测断开）
+// readPump 读取客户端的认证和订阅请求，并检测断开
 func (c *Client) readPump() {
 	defer func() {
+		c.stopExpiry()
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			break
 		}
+		c.handleMessage(data)
 	}
 }
 
@@ -0,0 +1,180 @@
+package wechat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 默认 wechatbot-webhook 地址与令牌
+const (
+	DefaultAPIURL = "http://127.0.0.1:3002"
+	DefaultToken  = "openclaw-wechat"
+)
+
+var (
+	// ErrUnavailable 微信桥接服务无法连接
+	ErrUnavailable = errors.New("微信服务不可用")
+	// ErrTimeout 微信桥接服务响应超时
+	ErrTimeout = errors.New("微信服务响应超时")
+	// ErrUnauthorized 令牌被微信桥接服务拒绝
+	ErrUnauthorized = errors.New("微信服务令牌无效")
+	// ErrNotLoggedIn 微信未登录
+	ErrNotLoggedIn = errors.New("微信未登录")
+	// ErrSendFailed 微信桥接服务返回发送失败
+	ErrSendFailed = errors.New("微信消息发送失败")
+)
+
+// Status 微信登录状态
+type Status struct {
+	Connected bool   `json:"connected"`
+	LoggedIn  bool   `json:"loggedIn"`
+	Name      string `json:"name"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Client wechatbot-webhook HTTP 客户端
+type Client struct {
+	BaseURL string
+	Token   string
+	http    *http.Client
+}
+
+// NewClient 创建微信桥接客户端，timeout 为单次请求超时
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	if token == "" {
+		token = DefaultToken
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+type apiResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+var contactNameRe = regexp.MustCompile(`Contact<([^>]*)>`)
+
+// Status 查询登录状态。服务可达但未登录时返回 Connected=true, LoggedIn=false
+func (c *Client) Status() (*Status, error) {
+	resp, err := c.do("GET", "/loginCheck", "", nil)
+	if err != nil {
+		return &Status{}, err
+	}
+	st := &Status{Connected: true, LoggedIn: resp.Success, Message: resp.Message}
+	if m := contactNameRe.FindStringSubmatch(resp.Message); m != nil {
+		st.Name = m[1]
+	}
+	return st, nil
+}
+
+// SendText 向联系人或群聊发送文本
+func (c *Client) SendText(to string, isRoom bool, content string) error {
+	return c.sendV2(to, isRoom, map[string]string{"type": "text", "content": content})
+}
+
+// SendFileURL 让桥接服务下载并发送远程文件
+func (c *Client) SendFileURL(to string, isRoom bool, fileURL string) error {
+	return c.sendV2(to, isRoom, map[string]string{"type": "fileUrl", "content": fileURL})
+}
+
+// SendFile 以 multipart 方式上传并发送文件
+func (c *Client) SendFile(to string, isRoom bool, filename string, r io.Reader) error {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.WriteField("to", to)
+	if isRoom {
+		w.WriteField("isRoom", "1")
+	} else {
+		w.WriteField("isRoom", "0")
+	}
+	part, err := w.CreateFormFile("content", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return err
+	}
+	w.Close()
+
+	resp, err := c.do("POST", "/webhook/msg", w.FormDataContentType(), &buf)
+	if err != nil {
+		return err
+	}
+	return sendResult(resp)
+}
+
+func (c *Client) sendV2(to string, isRoom bool, data map[string]string) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"to":     to,
+		"isRoom": isRoom,
+		"data":   data,
+	})
+	resp, err := c.do("POST", "/webhook/msg/v2", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	return sendResult(resp)
+}
+
+func sendResult(resp *apiResponse) error {
+	if resp.Success {
+		return nil
+	}
+	msg := strings.ToLower(resp.Message)
+	if strings.Contains(msg, "login") || strings.Contains(resp.Message, "登录") {
+		return fmt.Errorf("%w: %s", ErrNotLoggedIn, resp.Message)
+	}
+	return fmt.Errorf("%w: %s", ErrSendFailed, resp.Message)
+}
+
+// do 发送请求并解析 {success, message} 响应，将传输层错误映射为包级错误
+func (c *Client) do(method, path, contentType string, body io.Reader) (*apiResponse, error) {
+	u := c.BaseURL + path + "?token=" + url.QueryEscape(c.Token)
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, ErrUnauthorized
+	}
+	var result apiResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("%w: HTTP %d", ErrSendFailed, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("微信服务响应解析失败: %w", err)
+	}
+	return &result, nil
+}